@@ -0,0 +1,54 @@
+package base
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 响应的Date头只精确到秒，小于该值的偏差被忽略
+	clockSkewResolution = time.Second
+	// ClockSkewWarnThreshold 本地时间与服务端时间的偏差超过该值时输出警告
+	ClockSkewWarnThreshold = 30 * time.Second
+)
+
+// Clock 记录本地时间与服务端时间的偏差，Now返回按服务端时间校正之后的时间。
+// nil的Clock不做校正，可以被多个goroutine同时使用
+type Clock struct {
+	offset int64
+}
+
+func NewClock() *Clock {
+	return &Clock{}
+}
+
+func (c *Clock) Now() time.Time {
+	return time.Now().Add(c.Offset())
+}
+
+// Offset 返回服务端时间减去本地时间的差值
+func (c *Clock) Offset() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&c.offset))
+}
+
+// Observe 根据在sent和received之间收到的响应的Date头更新偏差，返回更新之后的偏差以及偏差是否发生了变化
+func (c *Clock) Observe(serverDate, sent, received time.Time) (time.Duration, bool) {
+	if c == nil || serverDate.IsZero() {
+		return 0, false
+	}
+	// Date头被截断到秒，用区间的中点估计服务端时间
+	mid := sent.Add(received.Sub(sent) / 2)
+	skew := serverDate.Add(clockSkewResolution / 2).Sub(mid)
+	if skew > -clockSkewResolution && skew < clockSkewResolution {
+		skew = 0
+	}
+	old := c.Offset()
+	if diff := skew - old; diff > -clockSkewResolution && diff < clockSkewResolution {
+		return old, false
+	}
+	atomic.StoreInt64(&c.offset, int64(skew))
+	return skew, true
+}
@@ -1,21 +1,35 @@
 package config
 
 import (
+	"net"
+	"net/http"
 	"time"
 
 	"github.com/qiniu/pandora-go-sdk/base"
 )
 
 type Config struct {
-	Endpoint         string
-	Ak               string
-	Sk               string
-	Logger           base.Logger
-	DialTimeout      time.Duration
-	ResponseTimeout  time.Duration
-	RequestRateLimit int64 //每秒请求数限制
-	FlowRateLimit    int64 //每秒流量限制(kb),若FlowRateLimit为100，则表示限速100KB/s
-	Gzip             bool
+	Endpoint          string
+	PipelineEndpoint  string //按服务设置的endpoint，不为空时优先于Endpoint
+	LogdbEndpoint     string
+	TsdbEndpoint      string
+	Region            string
+	Ak                string
+	Sk                string
+	Credentials       base.CredentialsProvider //不为nil时每次请求都从中读取AK/SK，优先于Ak和Sk
+	Logger            base.Logger
+	DialTimeout       time.Duration
+	ResponseTimeout   time.Duration
+	RequestRateLimit  int64              //每秒请求数限制
+	FlowRateLimit     int64              //每秒流量限制(kb),若FlowRateLimit为100，则表示限速100KB/s
+	AdaptiveRateLimit *AdaptiveRateLimit //不为nil时根据服务端的限流响应自动调整RequestRateLimit和FlowRateLimit
+	Gzip              bool
+	RetryPolicy       *RetryPolicy
+	Interceptors      []base.Interceptor //按顺序包装每一次请求，可以修改请求和观察响应
+	Metrics           base.MetricsCollector
+	Tracer            base.Tracer  //为nil时不记录trace
+	HTTPClient        *http.Client //为nil时每个client根据DialTimeout和ResponseTimeout创建自己的http.Client
+	Clock             *base.Clock  //根据响应的Date头校正签名使用的时间，为nil时使用本地时间
 }
 
 const (
@@ -27,6 +41,7 @@ func NewConfig() *Config {
 	return &Config{
 		DialTimeout:     defaultDialTimeout,
 		ResponseTimeout: defaultResponseTimeout,
+		Clock:           base.NewClock(),
 	}
 }
 
@@ -35,11 +50,29 @@ func (c *Config) WithEndpoint(endpoint string) *Config {
 	return c
 }
 
+func (c *Config) WithRegion(region string) *Config {
+	c.Region = region
+	return c
+}
+
 func (c *Config) WithAccessKeySecretKey(ak, sk string) *Config {
 	c.Ak, c.Sk = ak, sk
 	return c
 }
 
+func (c *Config) WithCredentialsProvider(p base.CredentialsProvider) *Config {
+	c.Credentials = p
+	return c
+}
+
+// RetrieveCredentials 返回签名使用的AK/SK，没有设置Credentials时返回Ak和Sk
+func (c *Config) RetrieveCredentials() (base.Credentials, error) {
+	if c.Credentials != nil {
+		return c.Credentials.Retrieve()
+	}
+	return base.Credentials{AccessKey: c.Ak, SecretKey: c.Sk}, nil
+}
+
 func (c *Config) WithDialTimeout(t time.Duration) *Config {
 	c.DialTimeout = t
 	return c
@@ -70,7 +103,81 @@ func (c *Config) WithFlowRateLimit(limit int64) *Config {
 	return c
 }
 
+func (c *Config) WithAdaptiveRateLimit(a *AdaptiveRateLimit) *Config {
+	c.AdaptiveRateLimit = a
+	return c
+}
+
 func (c *Config) WithGzipData(enable bool) *Config {
 	c.Gzip = enable
 	return c
 }
+
+func (c *Config) WithRetryPolicy(p *RetryPolicy) *Config {
+	c.RetryPolicy = p
+	return c
+}
+
+func (c *Config) WithInterceptors(interceptors ...base.Interceptor) *Config {
+	c.Interceptors = append(c.Interceptors, interceptors...)
+	return c
+}
+
+func (c *Config) WithMetrics(m base.MetricsCollector) *Config {
+	c.Metrics = m
+	return c
+}
+
+func (c *Config) WithTracer(t base.Tracer) *Config {
+	c.Tracer = t
+	return c
+}
+
+func (c *Config) WithHTTPClient(client *http.Client) *Config {
+	c.HTTPClient = client
+	return c
+}
+
+// NewTransport 根据DialTimeout和ResponseTimeout创建一个http.Transport
+func (c *Config) NewTransport() *http.Transport {
+	return &http.Transport{
+		Dial: (&net.Dialer{
+			Timeout:   c.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+		ResponseHeaderTimeout: c.ResponseTimeout,
+	}
+}
+
+// NewHTTPClient 返回HTTPClient，没有设置时使用NewTransport创建一个新的http.Client
+func (c *Config) NewHTTPClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Transport: c.NewTransport()}
+}
+
+const (
+	ServicePipeline = "pipeline"
+	ServiceLogdb    = "logdb"
+	ServiceTsdb     = "tsdb"
+)
+
+// ForService 返回service使用的配置，设置了对应服务的endpoint时返回一个Endpoint被替换的副本
+func (c *Config) ForService(service string) *Config {
+	var endpoint string
+	switch service {
+	case ServicePipeline:
+		endpoint = c.PipelineEndpoint
+	case ServiceLogdb:
+		endpoint = c.LogdbEndpoint
+	case ServiceTsdb:
+		endpoint = c.TsdbEndpoint
+	}
+	if endpoint == "" || endpoint == c.Endpoint {
+		return c
+	}
+	cp := *c
+	cp.Endpoint = endpoint
+	return &cp
+}
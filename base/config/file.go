@@ -0,0 +1,280 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 读取配置使用的环境变量
+const (
+	EnvPipelineHost = "PIPELINE_HOST"
+	EnvLogdbHost    = "LOGDB_HOST"
+	EnvTsdbHost     = "TSDB_HOST"
+	EnvAccessKey    = "ACCESS_KEY"
+	EnvSecretKey    = "SECRET_KEY"
+	EnvRegion       = "REGION"
+	EnvProfile      = "PANDORA_PROFILE"
+	EnvConfigFile   = "PANDORA_CONFIG_FILE"
+)
+
+const DefaultProfile = "default"
+
+// FromEnv 从环境变量读取配置。如果存在配置文件(PANDORA_CONFIG_FILE，默认为~/.pandora/config)，
+// 先读取PANDORA_PROFILE指定的profile，再用环境变量覆盖，凭证的优先级为环境变量、配置文件
+func FromEnv() (*Config, error) {
+	c := NewConfig()
+	path := os.Getenv(EnvConfigFile)
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, ".pandora", "config")
+		}
+	}
+	if path != "" {
+		if _, err := os.Stat(path); err == nil {
+			if err = c.loadFile(path, os.Getenv(EnvProfile)); err != nil {
+				return nil, err
+			}
+		} else if os.Getenv(EnvConfigFile) != "" {
+			return nil, err
+		}
+	}
+	c.loadEnv()
+	return c, nil
+}
+
+// LoadFile 读取配置文件中的profile，profile为空时使用PANDORA_PROFILE，仍为空则使用default，
+// 此时配置文件中没有default不会返回错误。
+// 根据扩展名识别格式：.json为JSON，.yaml和.yml为YAML，其他为INI。
+// 环境变量中的ACCESS_KEY和SECRET_KEY优先于配置文件中的凭证
+func LoadFile(path, profile string) (*Config, error) {
+	c := NewConfig()
+	if err := c.loadFile(path, profile); err != nil {
+		return nil, err
+	}
+	c.loadCredentialsFromEnv()
+	return c, nil
+}
+
+func (c *Config) loadEnv() {
+	if v := os.Getenv(EnvPipelineHost); v != "" {
+		c.PipelineEndpoint = v
+	}
+	if v := os.Getenv(EnvLogdbHost); v != "" {
+		c.LogdbEndpoint = v
+	}
+	if v := os.Getenv(EnvTsdbHost); v != "" {
+		c.TsdbEndpoint = v
+	}
+	if v := os.Getenv(EnvRegion); v != "" {
+		c.Region = v
+	}
+	c.loadCredentialsFromEnv()
+}
+
+func (c *Config) loadCredentialsFromEnv() {
+	ak, sk := os.Getenv(EnvAccessKey), os.Getenv(EnvSecretKey)
+	if ak != "" && sk != "" {
+		c.Ak, c.Sk = ak, sk
+	}
+}
+
+func (c *Config) loadFile(path, profile string) error {
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	// 只有明确指定的profile不存在时才返回错误，没有default的配置文件不影响使用环境变量
+	explicit := profile != ""
+	if !explicit {
+		profile = DefaultProfile
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var profiles map[string]map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		profiles, err = parseJSON(data)
+	case ".yaml", ".yml":
+		profiles, err = parseYAML(data)
+	default:
+		profiles, err = parseINI(data)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s failed, %v", path, err)
+	}
+	values, ok := profiles[profile]
+	if !ok {
+		if !explicit {
+			return nil
+		}
+		return fmt.Errorf("profile %s not found in config file %s", profile, path)
+	}
+	if err = c.apply(values); err != nil {
+		return fmt.Errorf("invalid profile %s in config file %s, %v", profile, path, err)
+	}
+	return nil
+}
+
+// apply 设置profile中的配置项，key不区分大小写，"-"和"_"等价
+func (c *Config) apply(values map[string]string) (err error) {
+	for key, value := range values {
+		switch strings.Replace(strings.ToLower(key), "-", "_", -1) {
+		case "endpoint":
+			c.Endpoint = value
+		case "pipeline_endpoint":
+			c.PipelineEndpoint = value
+		case "logdb_endpoint":
+			c.LogdbEndpoint = value
+		case "tsdb_endpoint":
+			c.TsdbEndpoint = value
+		case "region":
+			c.Region = value
+		case "access_key", "ak":
+			c.Ak = value
+		case "secret_key", "sk":
+			c.Sk = value
+		case "dial_timeout":
+			c.DialTimeout, err = parseDuration(value)
+		case "response_timeout":
+			c.ResponseTimeout, err = parseDuration(value)
+		case "request_rate_limit":
+			c.RequestRateLimit, err = strconv.ParseInt(value, 10, 64)
+		case "flow_rate_limit":
+			c.FlowRateLimit, err = strconv.ParseInt(value, 10, 64)
+		case "gzip":
+			c.Gzip, err = strconv.ParseBool(value)
+		default:
+			return fmt.Errorf("unknown key %s", key)
+		}
+		if err != nil {
+			return fmt.Errorf("key %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// parseDuration 支持10s、500ms这样的格式，不带单位时表示秒
+func parseDuration(s string) (time.Duration, error) {
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(n * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+func parseJSON(data []byte) (map[string]map[string]string, error) {
+	var raw map[string]map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	profiles := make(map[string]map[string]string, len(raw))
+	for name, values := range raw {
+		profiles[name] = make(map[string]string, len(values))
+		for k, v := range values {
+			profiles[name][k] = fmt.Sprint(v)
+		}
+	}
+	return profiles, nil
+}
+
+// parseINI 解析[profile]分段的key = value，段名也可以写成[profile staging]
+func parseINI(data []byte) (map[string]map[string]string, error) {
+	profiles := map[string]map[string]string{}
+	var current map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line[1:len(line)-1]), "profile "))
+			current = map[string]string{}
+			profiles[name] = current
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i < 0 || current == nil {
+			return nil, fmt.Errorf("line %d: invalid line %q", n, line)
+		}
+		current[strings.TrimSpace(line[:i])] = unquote(strings.TrimSpace(line[i+1:]))
+	}
+	return profiles, scanner.Err()
+}
+
+// parseYAML 只支持YAML的一个子集：两层的映射，第一层为profile名，第二层为配置项，
+// 值为单行的标量，可以用单引号或者双引号包含"#"等字符，更深的嵌套、列表和多行的值都会返回错误
+func parseYAML(data []byte) (map[string]map[string]string, error) {
+	profiles := map[string]map[string]string{}
+	var current map[string]string
+	indent := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		text := stripComment(scanner.Text())
+		line := strings.TrimSpace(text)
+		if line == "" || line == "---" {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: invalid line %q", n, line)
+		}
+		key, value := unquote(strings.TrimSpace(line[:i])), unquote(strings.TrimSpace(line[i+1:]))
+		lineIndent := len(text) - len(strings.TrimLeft(text, " \t"))
+		if lineIndent == 0 {
+			if value != "" {
+				return nil, fmt.Errorf("line %d: profile %s should be a mapping", n, key)
+			}
+			current = map[string]string{}
+			profiles[key] = current
+			indent = 0
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: key %s outside of profile", n, key)
+		}
+		if indent == 0 {
+			indent = lineIndent
+		}
+		if lineIndent != indent {
+			return nil, fmt.Errorf("line %d: nested mapping under profile is not supported", n)
+		}
+		current[key] = value
+	}
+	return profiles, scanner.Err()
+}
+
+// stripComment 去掉行首或者空白之后的"#"开始的注释，引号中的"#"不是注释
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Setenv(EnvAccessKey, "")
+	t.Setenv(EnvSecretKey, "")
+	t.Setenv(EnvProfile, "")
+
+	files := map[string]string{
+		"config.json": `{
+	"default": {"pipeline_endpoint": "https://pipeline.qiniu.com"},
+	"staging": {
+		"pipeline_endpoint": "http://pipeline.staging:9999",
+		"logdb_endpoint": "http://logdb.staging:9999",
+		"region": "nb",
+		"access_key": "ak",
+		"secret_key": "sk",
+		"dial_timeout": "5s",
+		"response_timeout": 60,
+		"request_rate_limit": 100,
+		"gzip": true
+	}
+}`,
+		"config.yaml": `# pandora profiles
+default:
+  pipeline_endpoint: https://pipeline.qiniu.com
+staging:
+  pipeline_endpoint: "http://pipeline.staging:9999"
+  logdb_endpoint: http://logdb.staging:9999 # logdb
+  region: nb
+  access_key: ak
+  secret_key: 'sk'
+  dial_timeout: 5s
+  response_timeout: 60
+  request_rate_limit: 100
+  gzip: true
+`,
+		"config": `[default]
+pipeline_endpoint = https://pipeline.qiniu.com
+
+[profile staging]
+pipeline_endpoint = http://pipeline.staging:9999
+logdb_endpoint = http://logdb.staging:9999
+region = nb
+access_key = ak
+secret_key = sk
+dial_timeout = 5s
+response_timeout = 60
+request_rate_limit = 100
+gzip = true
+`,
+	}
+	for name, content := range files {
+		path := writeConfigFile(t, name, content)
+		c, err := LoadFile(path, "staging")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if c.PipelineEndpoint != "http://pipeline.staging:9999" || c.LogdbEndpoint != "http://logdb.staging:9999" ||
+			c.Region != "nb" || c.Ak != "ak" || c.Sk != "sk" || c.DialTimeout != 5*time.Second ||
+			c.ResponseTimeout != time.Minute || c.RequestRateLimit != 100 || !c.Gzip {
+			t.Errorf("%s: unexpected config %+v", name, c)
+		}
+		if got := c.ForService(ServiceLogdb).Endpoint; got != "http://logdb.staging:9999" {
+			t.Errorf("%s: expect logdb endpoint, got %s", name, got)
+		}
+
+		c, err = LoadFile(path, "")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if c.PipelineEndpoint != "https://pipeline.qiniu.com" || c.DialTimeout != defaultDialTimeout {
+			t.Errorf("%s: unexpected default profile %+v", name, c)
+		}
+		if _, err = LoadFile(path, "production"); err == nil {
+			t.Errorf("%s: expect error for unknown profile", name)
+		}
+	}
+}
+
+func TestLoadYAMLQuotedComment(t *testing.T) {
+	t.Setenv(EnvAccessKey, "")
+	t.Setenv(EnvSecretKey, "")
+	path := writeConfigFile(t, "config.yml", `default:
+    access_key: "ak #1" # access key
+    secret_key: 's#k'
+    region: nb#1 # region
+`)
+	c, err := LoadFile(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Ak != "ak #1" || c.Sk != "s#k" || c.Region != "nb#1" {
+		t.Errorf("unexpected config %+v", c)
+	}
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown.ini": "[default]\nunknown_key = 1\n",
+		"timeout.ini": "[default]\ndial_timeout = soon\n",
+		"nokey.ini":   "endpoint = http://127.0.0.1\n",
+		"flat.yaml":   "endpoint: http://127.0.0.1\n",
+		"nested.yaml": "default:\n  region: nb\n  rate_limit:\n    request: 100\n",
+		"list.yaml":   "default:\n  endpoints:\n  - http://127.0.0.1\n",
+		"bad.json":    `{"default": "http://127.0.0.1"}`,
+	}
+	for name, content := range tests {
+		if _, err := LoadFile(writeConfigFile(t, name, content), ""); err == nil {
+			t.Errorf("%s: expect error", name)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	path := writeConfigFile(t, "config", "[default]\naccess_key = file_ak\nsecret_key = file_sk\n\n[staging]\nregion = file\ntsdb_endpoint = http://tsdb.staging\n")
+	t.Setenv(EnvConfigFile, path)
+	t.Setenv(EnvProfile, "staging")
+	t.Setenv(EnvPipelineHost, "http://127.0.0.1:9999")
+	t.Setenv(EnvLogdbHost, "")
+	t.Setenv(EnvTsdbHost, "")
+	t.Setenv(EnvRegion, "env")
+	t.Setenv(EnvAccessKey, "env_ak")
+	t.Setenv(EnvSecretKey, "env_sk")
+
+	c, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PipelineEndpoint != "http://127.0.0.1:9999" || c.TsdbEndpoint != "http://tsdb.staging" ||
+		c.Region != "env" || c.Ak != "env_ak" || c.Sk != "env_sk" {
+		t.Errorf("unexpected config %+v", c)
+	}
+
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvAccessKey, "")
+	if c, err = FromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Ak != "file_ak" || c.Sk != "file_sk" {
+		t.Errorf("expect credentials from file, got %s/%s", c.Ak, c.Sk)
+	}
+
+	// 配置文件中只有命名的profile时，没有指定profile不影响读取环境变量
+	named := writeConfigFile(t, "named", "[staging]\nregion = file\n")
+	t.Setenv(EnvConfigFile, named)
+	if c, err = FromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Region != "env" {
+		t.Errorf("expect region from env, got %s", c.Region)
+	}
+	t.Setenv(EnvProfile, "production")
+	if _, err = FromEnv(); err == nil {
+		t.Error("expect error for missing explicit profile")
+	}
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvConfigFile, path)
+
+	os.Remove(path)
+	if _, err = FromEnv(); err == nil {
+		t.Error("expect error for missing config file")
+	}
+}
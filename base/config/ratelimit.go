@@ -0,0 +1,65 @@
+package config
+
+import (
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base/ratelimit"
+)
+
+// AdaptiveRateLimit 描述RequestRateLimit和FlowRateLimit的自适应模式：服务端返回429、503或者响应时间超过
+// LatencyThreshold时按DecreaseFactor降低速率，请求成功时逐步恢复，速率始终在Min和Max之间
+type AdaptiveRateLimit struct {
+	MinRequestRate   int64         // 默认为RequestRateLimit的1/10
+	MaxRequestRate   int64         // 默认为RequestRateLimit
+	RequestIncrease  int64         // 每次成功的请求增加的请求速率，默认为MaxRequestRate的1/100，至少为1
+	MinFlowRate      int64         // 单位为KB/s，默认为FlowRateLimit的1/10
+	MaxFlowRate      int64         // 单位为KB/s，默认为FlowRateLimit
+	FlowIncrease     int64         // 单位为KB/s，每次成功的请求增加的流量速率，默认为MaxFlowRate的1/100
+	DecreaseFactor   float64       // 取值(0, 1)，默认为0.5
+	Cooldown         time.Duration // 两次降速之间的最小间隔，默认为1秒
+	LatencyThreshold time.Duration // 大于0时，响应时间(不包含限速等待)超过该值也会降低速率
+}
+
+// IsThrottled 判断一次请求的响应是否说明服务端正在限流或者过载
+func (a *AdaptiveRateLimit) IsThrottled(statusCode int, latency time.Duration) bool {
+	if statusCode == 429 || statusCode == 503 {
+		return true
+	}
+	return a.LatencyThreshold > 0 && latency > a.LatencyThreshold
+}
+
+// NewRequestLimiter 按RequestRateLimit创建请求数限速器，RequestRateLimit小于等于0时返回nil
+func (c *Config) NewRequestLimiter() *ratelimit.Limiter {
+	if c.RequestRateLimit <= 0 {
+		return nil
+	}
+	l := ratelimit.NewLimiter(c.RequestRateLimit)
+	if a := c.AdaptiveRateLimit; a != nil {
+		l.SetAdaptive(&ratelimit.AdaptiveConfig{
+			MinRate:        a.MinRequestRate,
+			MaxRate:        a.MaxRequestRate,
+			Increase:       a.RequestIncrease,
+			DecreaseFactor: a.DecreaseFactor,
+			Cooldown:       a.Cooldown,
+		})
+	}
+	return l
+}
+
+// NewFlowLimiter 按FlowRateLimit创建流量限速器，单位为字节，FlowRateLimit小于等于0时返回nil
+func (c *Config) NewFlowLimiter() *ratelimit.Limiter {
+	if c.FlowRateLimit <= 0 {
+		return nil
+	}
+	l := ratelimit.NewLimiter(1024 * c.FlowRateLimit)
+	if a := c.AdaptiveRateLimit; a != nil {
+		l.SetAdaptive(&ratelimit.AdaptiveConfig{
+			MinRate:        1024 * a.MinFlowRate,
+			MaxRate:        1024 * a.MaxFlowRate,
+			Increase:       1024 * a.FlowIncrease,
+			DecreaseFactor: a.DecreaseFactor,
+			Cooldown:       a.Cooldown,
+		})
+	}
+	return l
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewAdaptiveLimiter(t *testing.T) {
+	c := NewConfig().WithRequestRateLimit(100).WithFlowRateLimit(1000).
+		WithAdaptiveRateLimit(&AdaptiveRateLimit{RequestIncrease: 5, FlowIncrease: 20, Cooldown: 3 * time.Second})
+
+	a := c.NewRequestLimiter().Adaptive()
+	if a == nil || a.MinRate != 10 || a.MaxRate != 100 || a.Increase != 5 || a.Cooldown != 3*time.Second {
+		t.Fatalf("unexpected request adaptive config %+v", a)
+	}
+	a = c.NewFlowLimiter().Adaptive()
+	if a == nil || a.MaxRate != 1024*1000 || a.Increase != 1024*20 || a.Cooldown != 3*time.Second {
+		t.Fatalf("unexpected flow adaptive config %+v", a)
+	}
+
+	// 没有设置时使用默认值
+	c.AdaptiveRateLimit = &AdaptiveRateLimit{}
+	a = c.NewRequestLimiter().Adaptive()
+	if a == nil || a.Increase != 1 || a.DecreaseFactor != 0.5 || a.Cooldown != time.Second {
+		t.Fatalf("unexpected default adaptive config %+v", a)
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+// RetryPolicy 描述请求失败之后的重试策略，Config.RetryPolicy为nil时不做任何重试
+type RetryPolicy struct {
+	MaxAttempts int           // 最多尝试的次数(包含第一次请求)，小于等于1表示不重试
+	BaseBackoff time.Duration // 第一次重试前的等待时间，之后每次重试等待时间翻倍
+	MaxBackoff  time.Duration // 单次等待时间的上限
+	Jitter      float64       // 取值[0, 1]，等待时间中随机抖动的比例
+
+	// Retryable 判断服务端返回的错误是否可以重试，为nil时使用DefaultRetryable
+	Retryable func(err *reqerr.RequestError) bool
+
+	// RetryNonIdempotent 为true时才会重试PostData、SendLog等非幂等的操作，重试可能导致数据重复
+	RetryNonIdempotent bool
+}
+
+const (
+	defaultMaxAttempts int           = 3
+	defaultBaseBackoff time.Duration = 100 * time.Millisecond
+	defaultMaxBackoff  time.Duration = 5 * time.Second
+	defaultJitter      float64       = 0.2
+)
+
+func NewRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: defaultMaxAttempts,
+		BaseBackoff: defaultBaseBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+		Jitter:      defaultJitter,
+	}
+}
+
+// DefaultRetryable 认为服务端内部错误、限流以及查询中断是暂时性的错误
+func DefaultRetryable(err *reqerr.RequestError) bool {
+	switch err.ErrorType {
+	case reqerr.InternalServerError, reqerr.QueryInterruptError:
+		return true
+	}
+	return err.StatusCode >= 500 || err.StatusCode == 429
+}
+
+func (p *RetryPolicy) IsRetryable(err *reqerr.RequestError) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return DefaultRetryable(err)
+}
+
+// Backoff 返回第attempt次请求失败之后需要等待的时间
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.BaseBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if p.Jitter > 0 && backoff > 0 {
+		jitter := time.Duration(p.Jitter * float64(backoff))
+		backoff = backoff - jitter + time.Duration(rand.Int63n(int64(2*jitter)+1))
+	}
+	return backoff
+}
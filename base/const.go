@@ -59,11 +59,15 @@ const (
 	OpListJobExports   string = "ListJobExports"
 	OpDeleteJobExport  string = "DeleteJobExport"
 	OpRetrieveSchema   string = "RetrieveSchema"
+	OpVerifyTransform  string = "VerifyTransform"
+	OpVerifyExport     string = "VerifyExport"
 
 	OpUpdateRepo        string = "UpdateRepo"
 	OpSendLog           string = "SendLog"
 	OpQueryLog          string = "QueryLog"
 	OpQueryHistogramLog string = "QueryHistogramLog"
+	OpPutRepoConfig     string = "PutRepoConfig"
+	OpGetRepoConfig     string = "GetRepoConfig"
 
 	OpUpdateRepoMetadata string = "UpdataRepoMetadata"
 	OpDeleteRepoMetadata string = "DeleteRepoMetadata"
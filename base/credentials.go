@@ -0,0 +1,133 @@
+package base
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Credentials struct {
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+func (c Credentials) Validate() error {
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return fmt.Errorf("access key and secret key should not be empty")
+	}
+	return nil
+}
+
+// CredentialsProvider 在每次发送请求之前被调用，返回签名使用的AK/SK，需要能被多个goroutine同时调用
+type CredentialsProvider interface {
+	Retrieve() (Credentials, error)
+}
+
+// StaticCredentialsProvider 总是返回固定的AK/SK
+type StaticCredentialsProvider struct {
+	Credentials Credentials
+}
+
+func NewStaticCredentialsProvider(ak, sk string) *StaticCredentialsProvider {
+	return &StaticCredentialsProvider{Credentials: Credentials{AccessKey: ak, SecretKey: sk}}
+}
+
+func (p *StaticCredentialsProvider) Retrieve() (Credentials, error) {
+	return p.Credentials, p.Credentials.Validate()
+}
+
+// EnvCredentialsProvider 每次都从环境变量读取AK/SK，默认为ACCESS_KEY和SECRET_KEY
+type EnvCredentialsProvider struct {
+	AccessKeyEnv string
+	SecretKeyEnv string
+}
+
+func NewEnvCredentialsProvider() *EnvCredentialsProvider {
+	return &EnvCredentialsProvider{AccessKeyEnv: "ACCESS_KEY", SecretKeyEnv: "SECRET_KEY"}
+}
+
+func (p *EnvCredentialsProvider) Retrieve() (Credentials, error) {
+	c := Credentials{AccessKey: os.Getenv(p.AccessKeyEnv), SecretKey: os.Getenv(p.SecretKeyEnv)}
+	if err := c.Validate(); err != nil {
+		return c, fmt.Errorf("read credentials from env %s and %s failed, %v", p.AccessKeyEnv, p.SecretKeyEnv, err)
+	}
+	return c, nil
+}
+
+// FileCredentialsProvider 从文件读取AK/SK，文件的修改时间或者大小变化之后会重新读取。
+// 文件可以是{"access_key": "...", "secret_key": "..."}格式的JSON，也可以是每行一个key = value
+type FileCredentialsProvider struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	creds   Credentials
+}
+
+func NewFileCredentialsProvider(path string) *FileCredentialsProvider {
+	return &FileCredentialsProvider{Path: path}
+}
+
+func (p *FileCredentialsProvider) Retrieve() (Credentials, error) {
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size && p.creds.Validate() == nil {
+		return p.creds, nil
+	}
+	data, err := ioutil.ReadFile(p.Path)
+	if err != nil {
+		return Credentials{}, err
+	}
+	creds, err := parseCredentials(data)
+	if err == nil {
+		err = creds.Validate()
+	}
+	if err != nil {
+		return Credentials{}, fmt.Errorf("read credentials from file %s failed, %v", p.Path, err)
+	}
+	p.creds, p.modTime, p.size = creds, info.ModTime(), info.Size()
+	return creds, nil
+}
+
+func parseCredentials(data []byte) (c Credentials, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		err = json.Unmarshal(data, &c)
+		return
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		i := strings.IndexAny(line, "=:")
+		if line == "" || line[0] == '#' || i < 0 {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(line[i+1:]), `"'`)
+		switch strings.ToLower(strings.TrimSpace(line[:i])) {
+		case "access_key", "ak":
+			c.AccessKey = value
+		case "secret_key", "sk":
+			c.SecretKey = value
+		}
+	}
+	err = scanner.Err()
+	return
+}
+
+// CredentialsFunc 把一个函数适配为CredentialsProvider
+type CredentialsFunc func() (Credentials, error)
+
+func (f CredentialsFunc) Retrieve() (Credentials, error) {
+	return f()
+}
@@ -0,0 +1,58 @@
+package base
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileCredentialsProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := ioutil.WriteFile(path, []byte("# pandora\naccess_key = ak1\nsecret_key = \"sk1\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := NewFileCredentialsProvider(path)
+	c, err := p.Retrieve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AccessKey != "ak1" || c.SecretKey != "sk1" {
+		t.Fatalf("unexpected credentials %+v", c)
+	}
+
+	if err = ioutil.WriteFile(path, []byte(`{"access_key": "ak2", "secret_key": "sk2"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err = os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = p.Retrieve(); err != nil {
+		t.Fatal(err)
+	}
+	if c.AccessKey != "ak2" || c.SecretKey != "sk2" {
+		t.Fatalf("expect rotated credentials, got %+v", c)
+	}
+
+	if err = ioutil.WriteFile(path, []byte("access_key = ak3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Retrieve(); err == nil {
+		t.Fatal("expect error for missing secret key")
+	}
+}
+
+func TestEnvCredentialsProvider(t *testing.T) {
+	t.Setenv("ACCESS_KEY", "ak")
+	t.Setenv("SECRET_KEY", "")
+	p := NewEnvCredentialsProvider()
+	if _, err := p.Retrieve(); err == nil {
+		t.Fatal("expect error for empty secret key")
+	}
+	t.Setenv("SECRET_KEY", "sk")
+	if c, err := p.Retrieve(); err != nil || c.AccessKey != "ak" || c.SecretKey != "sk" {
+		t.Fatalf("unexpected credentials %+v, err %v", c, err)
+	}
+}
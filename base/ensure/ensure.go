@@ -0,0 +1,122 @@
+// Package ensure 实现pipeline、logdb和tsdb共用的EnsureRepo流程：repo不存在时创建，
+// 已存在时与期望的schema比较，只添加缺少的字段，存在冲突时不修改repo
+package ensure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+// Conflict 描述一个已存在的字段与期望的字段不兼容，Key为字段路径，嵌套字段以"."分隔。
+// Existing和Desired是各个服务中字段的定义，例如pipeline.RepoSchemaEntry或者tsdb中metadata的值
+type Conflict struct {
+	Key      string
+	Existing interface{}
+	Desired  interface{}
+	Reason   string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: %s", c.Key, c.Reason)
+}
+
+// Diff 是期望的schema与repo当前schema的差异
+type Diff struct {
+	// Added 是repo中缺少的字段，嵌套字段以"."分隔
+	Added []string
+	// Extra 是repo中存在但期望的schema中没有的字段，不会被删除
+	Extra []string
+	// Conflicts 是无法通过更新repo解决的差异
+	Conflicts []Conflict
+}
+
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Extra) == 0 && len(d.Conflicts) == 0
+}
+
+// DiffKeys 按key比较两组字段：desired中有而existing中没有的key计入Added，existing中有而desired中没有的key计入Extra，
+// 每个desired中的key都会调用match(i, j)，i和j分别是该key在existing和desired中的下标，existing中不存在时i为-1
+func DiffKeys(prefix string, existing, desired []string, diff *Diff, match func(i, j int)) {
+	index := make(map[string]int, len(existing))
+	for i, k := range existing {
+		index[k] = i
+	}
+	wanted := make(map[string]bool, len(desired))
+	for j, k := range desired {
+		wanted[k] = true
+		i, ok := index[k]
+		if !ok {
+			diff.Added = append(diff.Added, prefix+k)
+			i = -1
+		}
+		match(i, j)
+	}
+	for _, k := range existing {
+		if !wanted[k] {
+			diff.Extra = append(diff.Extra, prefix+k)
+		}
+	}
+}
+
+type Output struct {
+	// Created 表示repo是本次调用创建的
+	Created bool
+	// Updated 表示本次调用添加了缺少的字段
+	Updated bool
+	Diff    Diff
+}
+
+// Repo 是EnsureRepo需要的各个服务的repo操作
+type Repo struct {
+	Name string
+	// Get 获取repo当前的定义，repo不存在时返回reqerr.ErrNoSuchRepo
+	Get func(ctx context.Context) error
+	// Create 按期望的schema创建repo
+	Create func(ctx context.Context) error
+	// Diff 比较Get获取的定义和期望的schema
+	Diff func() Diff
+	// Update 添加Diff中缺少的字段
+	Update func(ctx context.Context) error
+}
+
+// Ensure 在repo不存在时创建repo；repo已存在时比较schema，只有在存在缺少的字段并且没有冲突时才调用Update。
+// 返回的output总是不为nil，存在冲突时error为reqerr.ErrSchemaConflict
+func Ensure(ctx context.Context, r Repo) (output *Output, err error) {
+	output = &Output{}
+	err = r.Get(ctx)
+	if errors.Is(err, reqerr.ErrNoSuchRepo) {
+		err = r.Create(ctx)
+		if err == nil {
+			output.Created = true
+			return
+		}
+		if !errors.Is(err, reqerr.ErrRepoAlreadyExists) {
+			return
+		}
+		// 与其他调用者同时创建了repo
+		err = r.Get(ctx)
+	}
+	if err != nil {
+		return
+	}
+
+	output.Diff = r.Diff()
+	if len(output.Diff.Conflicts) > 0 {
+		conflicts := make([]string, len(output.Diff.Conflicts))
+		for i, c := range output.Diff.Conflicts {
+			conflicts[i] = c.String()
+		}
+		err = reqerr.NewSchemaConflict(r.Name, strings.Join(conflicts, "; "))
+		return
+	}
+	if len(output.Diff.Added) == 0 {
+		return
+	}
+	err = r.Update(ctx)
+	output.Updated = err == nil
+	return
+}
@@ -0,0 +1,93 @@
+package ensure
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+// fakeRepo 按顺序记录被调用的操作
+type fakeRepo struct {
+	getErrs   []error
+	createErr error
+	diff      Diff
+	calls     []string
+}
+
+func (f *fakeRepo) repo() Repo {
+	return Repo{
+		Name: "repo",
+		Get: func(context.Context) (err error) {
+			f.calls = append(f.calls, "get")
+			err, f.getErrs = f.getErrs[0], f.getErrs[1:]
+			return
+		},
+		Create: func(context.Context) error {
+			f.calls = append(f.calls, "create")
+			return f.createErr
+		},
+		Diff: func() Diff {
+			f.calls = append(f.calls, "diff")
+			return f.diff
+		},
+		Update: func(context.Context) error {
+			f.calls = append(f.calls, "update")
+			return nil
+		},
+	}
+}
+
+func TestDiffKeys(t *testing.T) {
+	var diff Diff
+	var matched [][2]int
+	DiffKeys("p.", []string{"a", "b", "c"}, []string{"c", "d", "a"}, &diff, func(i, j int) {
+		matched = append(matched, [2]int{i, j})
+	})
+	if exp := []string{"p.d"}; !reflect.DeepEqual(diff.Added, exp) {
+		t.Errorf("expect added %v, got %v", exp, diff.Added)
+	}
+	if exp := []string{"p.b"}; !reflect.DeepEqual(diff.Extra, exp) {
+		t.Errorf("expect extra %v, got %v", exp, diff.Extra)
+	}
+	if exp := [][2]int{{2, 0}, {-1, 1}, {0, 2}}; !reflect.DeepEqual(matched, exp) {
+		t.Errorf("expect matched %v, got %v", exp, matched)
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	noSuchRepo := reqerr.New("E18102: repo not found", "", "", 404)
+	noSuchRepo.ErrorType = reqerr.NoSuchRepoError
+	alreadyExists := reqerr.New("E18101: repo already exists", "", "", 409)
+	alreadyExists.ErrorType = reqerr.RepoAlreadyExistsError
+
+	cases := []struct {
+		name   string
+		repo   *fakeRepo
+		calls  []string
+		output Output
+		err    error
+	}{
+		{"create", &fakeRepo{getErrs: []error{noSuchRepo}}, []string{"get", "create"}, Output{Created: true}, nil},
+		{"created concurrently", &fakeRepo{getErrs: []error{noSuchRepo, nil}, createErr: alreadyExists, diff: Diff{Added: []string{"f1"}}},
+			[]string{"get", "create", "get", "diff", "update"}, Output{Updated: true, Diff: Diff{Added: []string{"f1"}}}, nil},
+		{"up to date", &fakeRepo{getErrs: []error{nil}, diff: Diff{Extra: []string{"f1"}}},
+			[]string{"get", "diff"}, Output{Diff: Diff{Extra: []string{"f1"}}}, nil},
+		{"conflict", &fakeRepo{getErrs: []error{nil}, diff: Diff{Added: []string{"f1"}, Conflicts: []Conflict{{Key: "f2", Reason: "changed"}}}},
+			[]string{"get", "diff"}, Output{Diff: Diff{Added: []string{"f1"}, Conflicts: []Conflict{{Key: "f2", Reason: "changed"}}}}, reqerr.ErrSchemaConflict},
+	}
+	for _, c := range cases {
+		output, err := Ensure(context.Background(), c.repo.repo())
+		if c.err == nil && err != nil || c.err != nil && !errors.Is(err, c.err) {
+			t.Errorf("%s: expect error %v, got %v", c.name, c.err, err)
+		}
+		if !reflect.DeepEqual(*output, c.output) {
+			t.Errorf("%s: expect output %+v, got %+v", c.name, c.output, *output)
+		}
+		if !reflect.DeepEqual(c.repo.calls, c.calls) {
+			t.Errorf("%s: expect calls %v, got %v", c.name, c.calls, c.repo.calls)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package base
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Operation 描述一次API调用，Path中包含了query string
+type Operation struct {
+	Name   string
+	Method string
+	Path   string
+}
+
+// Call 是拦截器看到的一次请求，每次重试都会生成一个新的Call。
+// 调用next之前可以修改Operation、HTTPRequest的header和Body，修改会在签名之前生效；
+// 每次尝试的Operation都是创建请求时Operation的副本，对HTTPRequest和Body的修改会保留到之后的重试。
+// 替换的Body应为未压缩的数据，开启Config.Gzip时会和原来的请求体一样被压缩；
+// next返回之后可以读取HTTPResponse、Error和Latency。
+type Call struct {
+	Context     context.Context
+	Operation   *Operation
+	Attempt     int
+	HTTPRequest *http.Request
+	Body        io.ReadSeeker
+
+	HTTPResponse *http.Response
+	Error        error
+	Latency      time.Duration
+}
+
+type Handler func(call *Call) error
+
+// Interceptor 包装一次请求的发送，不调用next时请求不会被发送，返回的error即为请求的结果
+type Interceptor func(call *Call, next Handler) error
+
+// Chain 把interceptors按顺序包装在h外面，第一个interceptor最先执行
+func Chain(interceptors []Interceptor, h Handler) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], h
+		h = func(call *Call) error {
+			return interceptor(call, next)
+		}
+	}
+	return h
+}
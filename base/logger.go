@@ -1,10 +1,12 @@
 package base
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 )
 
 type LogLevelType uint
@@ -57,6 +59,59 @@ type Logger interface {
 	Fatalf(format string, v ...interface{})
 }
 
+// Field 是结构化日志中的一个键值对
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// StructuredLogger 是可选的接口，Logger实现了该接口时，SDK输出的日志会带上op、status、reqid等字段，
+// 方便在日志系统中按字段过滤
+type StructuredLogger interface {
+	Logger
+
+	Log(level LogLevelType, msg string, fields ...Field)
+}
+
+// LogFields 通过l输出一条带字段的日志，l没有实现StructuredLogger时，字段以key=value的形式追加在msg之后
+func LogFields(l Logger, level LogLevelType, msg string, fields ...Field) {
+	if sl, ok := l.(StructuredLogger); ok {
+		sl.Log(level, msg, fields...)
+		return
+	}
+	msg += formatFields(fields)
+	switch level {
+	case LogDebug:
+		l.Debug(msg)
+	case LogInfo:
+		l.Info(msg)
+	case LogWarn:
+		l.Warn(msg)
+	case LogError:
+		l.Error(msg)
+	case LogPanic:
+		l.Panic(msg)
+	case LogFatal:
+		l.Fatal(msg)
+	}
+}
+
+func formatFields(fields []Field) string {
+	var buf bytes.Buffer
+	for _, f := range fields {
+		v := fmt.Sprint(f.Value)
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(&buf, " %s=%s", f.Key, v)
+	}
+	return buf.String()
+}
+
 func SetLogger(l Logger) { pipelineLogger = l }
 
 var (
@@ -136,7 +191,7 @@ func (l *DefaultLogger) Errorf(format string, v ...interface{}) {
 
 func (l *DefaultLogger) Panic(v ...interface{}) {
 	if l.level.AtMost(LogPanic) {
-		l.Logger.Panic(v)
+		l.Logger.Panic(v...)
 	}
 }
 
@@ -160,6 +215,31 @@ func (l *DefaultLogger) Fatalf(format string, v ...interface{}) {
 	}
 }
 
+var levelNames = map[LogLevelType]string{
+	LogDebug: "DEBUG",
+	LogInfo:  "INFO",
+	LogWarn:  "WARN",
+	LogError: "ERROR",
+	LogPanic: "PANIC",
+	LogFatal: "FATAL",
+}
+
+func (l *DefaultLogger) Log(level LogLevelType, msg string, fields ...Field) {
+	if !l.level.AtMost(level) {
+		return
+	}
+	msg += formatFields(fields)
+	switch level {
+	case LogPanic:
+		l.Logger.Panic(msg)
+	case LogFatal:
+		l.Output(calldepth, header("FATAL", msg))
+		os.Exit(1)
+	default:
+		l.Output(calldepth, header(levelNames[level], msg))
+	}
+}
+
 func header(lvl, msg string) string {
 	return fmt.Sprintf("%s: %s", lvl, msg)
 }
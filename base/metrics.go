@@ -0,0 +1,23 @@
+package base
+
+import "time"
+
+// RequestStats 是一次请求尝试的统计信息，重试时每次尝试都会单独上报
+type RequestStats struct {
+	Operation  string
+	Attempt    int
+	StatusCode int //没有收到响应时为0
+	Error      error
+	BytesSent  int64
+	Latency    time.Duration
+	// RateLimitWait 是在RequestRateLimit和FlowRateLimit限速器上等待的时间
+	RateLimitWait time.Duration
+	// RequestRateLimit和FlowRateLimit是请求发送时限速器的速率，开启自适应限速时会变化，没有限速时为0
+	RequestRateLimit int64 // 每秒请求数
+	FlowRateLimit    int64 // 每秒字节数
+}
+
+// MetricsCollector 收集SDK发出的请求的统计信息，会被多个goroutine同时调用
+type MetricsCollector interface {
+	ObserveRequest(stats *RequestStats)
+}
@@ -0,0 +1,218 @@
+// Package metrics 提供base.MetricsCollector的默认实现，以Prometheus文本格式输出统计信息
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+const (
+	defaultNamespace = "pandora_sdk"
+
+	// ClientErrorType 是没有拿到服务端错误时使用的error_type，例如网络错误和context取消
+	ClientErrorType = "ClientError"
+)
+
+// DefaultBuckets 是请求耗时和限速等待时间直方图的默认分桶，单位为秒
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type errorKey struct {
+	op        string
+	errorType string
+}
+
+type histogram struct {
+	counts []uint64 //与buckets一一对应，不是累加值
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+	for i, b := range buckets {
+		if v <= b {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Collector 按Operation统计请求数、错误数、发送字节数、重试次数、请求耗时、限速等待时间以及当前的限速速率，
+// 同时实现了http.Handler，可以直接注册到/metrics
+type Collector struct {
+	Namespace string
+	Buckets   []float64 //需要在开始统计之前设置，单位为秒，按升序排列
+
+	mu        sync.Mutex
+	requests  map[string]uint64
+	errors    map[errorKey]uint64
+	bytesSent map[string]uint64
+	retries   map[string]uint64
+	latency   map[string]*histogram
+	wait      map[string]*histogram
+	reqRate   map[string]uint64
+	flowRate  map[string]uint64
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		Namespace: defaultNamespace,
+		Buckets:   DefaultBuckets,
+		requests:  map[string]uint64{},
+		errors:    map[errorKey]uint64{},
+		bytesSent: map[string]uint64{},
+		retries:   map[string]uint64{},
+		latency:   map[string]*histogram{},
+		wait:      map[string]*histogram{},
+		reqRate:   map[string]uint64{},
+		flowRate:  map[string]uint64{},
+	}
+}
+
+func (c *Collector) ObserveRequest(stats *base.RequestStats) {
+	op := stats.Operation
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests[op]++
+	if stats.Error != nil {
+		c.errors[errorKey{op, errorType(stats.Error)}]++
+	}
+	if stats.BytesSent > 0 {
+		c.bytesSent[op] += uint64(stats.BytesSent)
+	}
+	if stats.Attempt > 1 {
+		c.retries[op]++
+	}
+	observe(c.latency, op, c.Buckets, stats.Latency)
+	observe(c.wait, op, c.Buckets, stats.RateLimitWait)
+	if stats.RequestRateLimit > 0 {
+		c.reqRate[op] = uint64(stats.RequestRateLimit)
+	}
+	if stats.FlowRateLimit > 0 {
+		c.flowRate[op] = uint64(stats.FlowRateLimit)
+	}
+}
+
+func observe(hs map[string]*histogram, op string, buckets []float64, d time.Duration) {
+	h, ok := hs[op]
+	if !ok {
+		h = &histogram{}
+		hs[op] = h
+	}
+	h.observe(buckets, d.Seconds())
+}
+
+func errorType(err error) string {
+	var reqErr *reqerr.RequestError
+	if errors.As(err, &reqErr) {
+		return reqerr.ErrorTypeName(reqErr.ErrorType)
+	}
+	return ClientErrorType
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(base.HTTPHeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	c.Write(bw)
+	bw.Flush()
+}
+
+// Write 以Prometheus文本格式输出当前的统计信息
+func (c *Collector) Write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeCounter(w, "requests_total", "Total number of requests sent, including retries.", c.requests)
+	c.writeHeader(w, "request_errors_total", "Total number of failed requests by error type.", "counter")
+	keys := make([]errorKey, 0, len(c.errors))
+	for k := range c.errors {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].op != keys[j].op {
+			return keys[i].op < keys[j].op
+		}
+		return keys[i].errorType < keys[j].errorType
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s_request_errors_total{op=%q,error_type=%q} %d\n", c.Namespace, k.op, k.errorType, c.errors[k])
+	}
+	c.writeCounter(w, "request_bytes_sent_total", "Total number of request body bytes sent.", c.bytesSent)
+	c.writeCounter(w, "request_retries_total", "Total number of retried requests.", c.retries)
+	c.writeHistogram(w, "request_duration_seconds", "Request latency in seconds.", c.latency)
+	c.writeHistogram(w, "ratelimit_wait_seconds", "Time spent waiting on rate limiters in seconds.", c.wait)
+	c.writeGauge(w, "ratelimit_requests_per_second", "Current request rate limit.", c.reqRate)
+	c.writeGauge(w, "ratelimit_bytes_per_second", "Current flow rate limit in bytes.", c.flowRate)
+}
+
+func (c *Collector) writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s_%s %s\n", c.Namespace, name, help)
+	fmt.Fprintf(w, "# TYPE %s_%s %s\n", c.Namespace, name, typ)
+}
+
+func (c *Collector) writeCounter(w io.Writer, name, help string, values map[string]uint64) {
+	c.writeHeader(w, name, help, "counter")
+	for _, op := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s_%s{op=%q} %d\n", c.Namespace, name, op, values[op])
+	}
+}
+
+func (c *Collector) writeGauge(w io.Writer, name, help string, values map[string]uint64) {
+	c.writeHeader(w, name, help, "gauge")
+	for _, op := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s_%s{op=%q} %d\n", c.Namespace, name, op, values[op])
+	}
+}
+
+func (c *Collector) writeHistogram(w io.Writer, name, help string, hs map[string]*histogram) {
+	c.writeHeader(w, name, help, "histogram")
+	ops := make([]string, 0, len(hs))
+	for op := range hs {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		h := hs[op]
+		var cumulative uint64
+		for i, b := range c.Buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_%s_bucket{op=%q,le=%q} %d\n", c.Namespace, name, op, formatFloat(b), cumulative)
+		}
+		fmt.Fprintf(w, "%s_%s_bucket{op=%q,le=\"+Inf\"} %d\n", c.Namespace, name, op, h.count)
+		fmt.Fprintf(w, "%s_%s_sum{op=%q} %s\n", c.Namespace, name, op, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_%s_count{op=%q} %d\n", c.Namespace, name, op, h.count)
+	}
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if strings.Contains(s, "e") {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return s
+}
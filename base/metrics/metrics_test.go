@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	c.Buckets = []float64{0.1, 1}
+	c.ObserveRequest(&base.RequestStats{
+		Operation:     base.OpPostData,
+		Attempt:       1,
+		StatusCode:    500,
+		Error:         &reqerr.RequestError{ErrorType: reqerr.InternalServerError},
+		BytesSent:     100,
+		Latency:       50 * time.Millisecond,
+		RateLimitWait: 2 * time.Second,
+	})
+	c.ObserveRequest(&base.RequestStats{
+		Operation:        base.OpPostData,
+		Attempt:          2,
+		StatusCode:       200,
+		BytesSent:        100,
+		Latency:          500 * time.Millisecond,
+		RequestRateLimit: 50,
+		FlowRateLimit:    1024,
+	})
+	c.ObserveRequest(&base.RequestStats{
+		Operation: base.OpGetRepo,
+		Attempt:   1,
+		Error:     errors.New("connection refused"),
+	})
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get(base.HTTPHeaderContentType); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %s", ct)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE pandora_sdk_requests_total counter",
+		`pandora_sdk_requests_total{op="PostData"} 2`,
+		`pandora_sdk_requests_total{op="GetRepo"} 1`,
+		`pandora_sdk_request_errors_total{op="GetRepo",error_type="ClientError"} 1`,
+		`pandora_sdk_request_errors_total{op="PostData",error_type="InternalServerError"} 1`,
+		`pandora_sdk_request_bytes_sent_total{op="PostData"} 200`,
+		`pandora_sdk_request_retries_total{op="PostData"} 1`,
+		"# TYPE pandora_sdk_request_duration_seconds histogram",
+		`pandora_sdk_request_duration_seconds_bucket{op="PostData",le="0.1"} 1`,
+		`pandora_sdk_request_duration_seconds_bucket{op="PostData",le="1"} 2`,
+		`pandora_sdk_request_duration_seconds_bucket{op="PostData",le="+Inf"} 2`,
+		`pandora_sdk_request_duration_seconds_sum{op="PostData"} 0.55`,
+		`pandora_sdk_request_duration_seconds_count{op="PostData"} 2`,
+		`pandora_sdk_ratelimit_wait_seconds_bucket{op="PostData",le="1"} 1`,
+		`pandora_sdk_ratelimit_wait_seconds_bucket{op="PostData",le="+Inf"} 2`,
+		"# TYPE pandora_sdk_ratelimit_requests_per_second gauge",
+		`pandora_sdk_ratelimit_requests_per_second{op="PostData"} 50`,
+		`pandora_sdk_ratelimit_bytes_per_second{op="PostData"} 1024`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expect line %q in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+//go:build otel
+// +build otel
+
+// Package oteltrace 把base.Tracer适配到OpenTelemetry，需要使用-tags otel编译，
+// 并且依赖go.opentelemetry.io/otel
+package oteltrace
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+)
+
+type Tracer struct {
+	Tracer     trace.Tracer
+	Propagator propagation.TextMapPropagator
+}
+
+// New 返回使用t创建span的Tracer，默认使用W3C Trace Context传播traceparent
+func New(t trace.Tracer) *Tracer {
+	return &Tracer{
+		Tracer:     t,
+		Propagator: propagation.TraceContext{},
+	}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, base.Span) {
+	ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, &otelSpan{span: span}
+}
+
+func (t *Tracer) Inject(ctx context.Context, header http.Header) {
+	t.Propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttributes(attrs ...base.Field) {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, f := range attrs {
+		kvs = append(kvs, keyValue(f))
+	}
+	s.span.SetAttributes(kvs...)
+}
+
+func (s *otelSpan) SetError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *otelSpan) End() {
+	s.span.End()
+}
+
+func keyValue(f base.Field) attribute.KeyValue {
+	switch v := f.Value.(type) {
+	case string:
+		return attribute.String(f.Key, v)
+	case int:
+		return attribute.Int(f.Key, v)
+	case int64:
+		return attribute.Int64(f.Key, v)
+	case float64:
+		return attribute.Float64(f.Key, v)
+	case bool:
+		return attribute.Bool(f.Key, v)
+	default:
+		return attribute.String(f.Key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,64 @@
+//go:build otel
+// +build otel
+
+package oteltrace
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := New(provider.Tracer("pandora"))
+
+	ctx, span := tracer.Start(context.Background(), "PostData")
+	span.SetAttributes(
+		base.Field{Key: base.AttrRepo, Value: "repo"},
+		base.Field{Key: base.AttrAttempts, Value: 2},
+		base.Field{Key: "custom", Value: []int{1}},
+	)
+	header := http.Header{}
+	tracer.Inject(ctx, header)
+	span.SetError(errors.New("post data failed"))
+	span.End()
+
+	if header.Get("traceparent") == "" {
+		t.Error("expect traceparent to be injected")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expect 1 span, got %d", len(spans))
+	}
+	s := spans[0]
+	if s.Name() != "PostData" || s.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span %s of kind %v", s.Name(), s.SpanKind())
+	}
+	if s.Status().Code != codes.Error || s.Status().Description != "post data failed" {
+		t.Errorf("expect error status, got %+v", s.Status())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range s.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if v := attrs[base.AttrRepo].AsString(); v != "repo" {
+		t.Errorf("expect repo attribute, got %q", v)
+	}
+	if v := attrs[base.AttrAttempts].AsInt64(); v != 2 {
+		t.Errorf("expect attempts attribute 2, got %d", v)
+	}
+	if v := attrs["custom"].AsString(); v != "[1]" {
+		t.Errorf("expect unsupported values to be formatted as string, got %q", v)
+	}
+}
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultDecreaseFactor = 0.5
+	defaultCooldown       = time.Second
+	defaultMinRateDivisor = 10
+	defaultIncreaseSteps  = 100
+)
+
+// AdaptiveConfig 描述Limiter的自适应模式：每次成功的请求把速率增加Increase，
+// 被服务端限流时把速率乘以DecreaseFactor(AIMD)，速率始终在[MinRate, MaxRate]之内
+type AdaptiveConfig struct {
+	MinRate        int64         // 速率下限，默认为MaxRate的1/10，至少为1
+	MaxRate        int64         // 速率上限，默认为开启自适应模式时的速率
+	Increase       int64         // 每次成功增加的速率，默认为MaxRate的1/100，至少为1
+	DecreaseFactor float64       // 取值(0, 1)，默认为0.5
+	Cooldown       time.Duration // 两次降速之间的最小间隔，避免同时失败的多个请求把速率降得过低，默认为1秒
+
+	burstRatio float64 // 开启自适应模式时burst与速率的比例，调整速率时burst按相同的比例调整
+}
+
+// SetAdaptive 开启自适应模式，cfg为nil时关闭自适应模式，当前的速率和burst保持不变
+func (l *Limiter) SetAdaptive(cfg *AdaptiveConfig) {
+	if cfg == nil {
+		l.adaptive.Store((*AdaptiveConfig)(nil))
+		return
+	}
+	c := *cfg
+	if c.MaxRate <= 0 {
+		c.MaxRate = l.GetRateLimit()
+	}
+	if c.MaxRate <= 0 {
+		// 不限速的Limiter没有调整的基准
+		l.adaptive.Store((*AdaptiveConfig)(nil))
+		return
+	}
+	if c.MinRate <= 0 {
+		c.MinRate = c.MaxRate / defaultMinRateDivisor
+	}
+	if c.MinRate < 1 {
+		c.MinRate = 1
+	}
+	if c.MinRate > c.MaxRate {
+		c.MinRate = c.MaxRate
+	}
+	if c.Increase <= 0 {
+		c.Increase = c.MaxRate / defaultIncreaseSteps
+	}
+	if c.Increase < 1 {
+		c.Increase = 1
+	}
+	if c.DecreaseFactor <= 0 || c.DecreaseFactor >= 1 {
+		c.DecreaseFactor = defaultDecreaseFactor
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = defaultCooldown
+	}
+	base := l.GetRateLimit()
+	if base <= 0 {
+		base = c.MaxRate
+	}
+	c.burstRatio = float64(l.Burst()) / float64(base)
+	l.adaptive.Store(&c)
+	l.adjust(func(rate int64) int64 { return rate })
+}
+
+// Adaptive 返回自适应模式的配置，没有开启时返回nil
+func (l *Limiter) Adaptive() *AdaptiveConfig {
+	c, _ := l.adaptive.Load().(*AdaptiveConfig)
+	return c
+}
+
+// OnSuccess 在请求成功之后调用，自适应模式下把速率增加Increase
+func (l *Limiter) OnSuccess() {
+	c := l.Adaptive()
+	if c == nil {
+		return
+	}
+	l.adjust(func(rate int64) int64 { return rate + c.Increase })
+}
+
+// OnThrottle 在服务端限流或者过载时调用，自适应模式下把速率乘以DecreaseFactor，
+// 距离上一次降速不足Cooldown时不做调整。返回是否降低了速率
+func (l *Limiter) OnThrottle() bool {
+	c := l.Adaptive()
+	if c == nil {
+		return false
+	}
+	now := l.elapsed()
+	last := atomic.LoadInt64(&l.lastDecrease)
+	if now-last < int64(c.Cooldown) || !atomic.CompareAndSwapInt64(&l.lastDecrease, last, now) {
+		return false
+	}
+	l.adjust(func(rate int64) int64 { return int64(math.Floor(float64(rate) * c.DecreaseFactor)) })
+	return true
+}
+
+// adjust 按f修改速率，结果限制在[MinRate, MaxRate]之内。burst随速率按比例调整，
+// 否则降速之后短暂的空闲仍然会积累与最高速率相当的令牌
+func (l *Limiter) adjust(f func(rate int64) int64) {
+	c := l.Adaptive()
+	if c == nil {
+		return
+	}
+	for {
+		old := atomic.LoadInt64(&l.rate)
+		if old <= 0 {
+			return
+		}
+		rate := f(old)
+		if rate < c.MinRate {
+			rate = c.MinRate
+		}
+		if rate > c.MaxRate {
+			rate = c.MaxRate
+		}
+		if rate == old {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&l.rate, old, rate) {
+			l.rescale(old, rate)
+			l.SetBurst(int64(math.Ceil(float64(rate) * c.burstRatio)))
+			return
+		}
+	}
+}
@@ -1,88 +1,226 @@
-//ratelimit and traffic control
+// ratelimit and traffic control
 package ratelimit
 
 import (
-	"sync"
+	"context"
+	"math"
+	"sync/atomic"
 	"time"
 )
 
+// Deprecated: Limiter不再使用后台goroutine定时补充额度，Window不再生效
 var Window = 50 * time.Millisecond
 
-// Limiter can limit multiple io.Reader(or io.Writer) within specific rate.
+// Limiter 是基于GCRA算法的令牌桶，每秒产生rate个令牌，最多积累burst个令牌。
+// 状态只保存在几个原子变量中，没有锁也没有后台goroutine，可以被多个goroutine同时使用。
+// 一次申请的令牌数可以超过burst，此时调用方需要等待超出部分按速率产生，而不是被拒绝。
 type Limiter struct {
-	capacity      int64
-	threshold     int64
-	cond          *sync.Cond
-	done          chan struct{}
-	ratePerSecond int64
+	rate  int64 // 每秒产生的令牌数，小于等于0时不限速
+	burst int64 // 桶的容量
+	tat   int64 // theoretical arrival time，相对于start的纳秒数，在此之前产生的令牌都已经被申请
+	start time.Time
+
+	adaptive     atomic.Value     // *AdaptiveConfig
+	lastDecrease int64            // 上一次降速的时间，相对于start的纳秒数
+	now          func() time.Time // 用于测试
 }
 
-// NewLimiter 限速的最小粒度是20
+// NewLimiter 返回每秒产生ratePerSecond个令牌的Limiter，burst等于ratePerSecond
 func NewLimiter(ratePerSecond int64) *Limiter {
-	quantity := (ratePerSecond*int64(Window) + int64(time.Second)) / int64(time.Second)
-	self := &Limiter{
-		ratePerSecond: ratePerSecond,
-		threshold:     ratePerSecond,
-		capacity:      ratePerSecond,
-		cond:          sync.NewCond(new(sync.Mutex)),
-		done:          make(chan struct{}, 1),
+	return NewLimiterWithBurst(ratePerSecond, ratePerSecond)
+}
+
+// NewLimiterWithBurst 返回每秒产生ratePerSecond个令牌、最多积累burst个令牌的Limiter，
+// 新创建的Limiter桶是满的
+func NewLimiterWithBurst(ratePerSecond, burst int64) *Limiter {
+	l := &Limiter{start: time.Now(), lastDecrease: math.MinInt64 / 2}
+	l.SetRate(ratePerSecond)
+	l.SetBurst(burst)
+	return l
+}
+
+// Reservation 是Reserve预留的令牌，在Delay之后才能使用
+type Reservation struct {
+	limiter *Limiter
+	cost    int64
+	delay   time.Duration
+}
+
+// Delay 返回预留时距离令牌可以使用还需要等待的时间
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel 归还预留的令牌，通常在放弃等待时调用，只能调用一次
+func (r *Reservation) Cancel() {
+	if r.cost > 0 {
+		r.limiter.refund(r.cost)
+		r.cost = 0
+	}
+}
+
+// Reserve 立即预留n个令牌并返回需要等待的时间，预留总是成功的
+func (l *Limiter) Reserve(n int64) *Reservation {
+	r := &Reservation{limiter: l}
+	if n <= 0 || l.GetRateLimit() <= 0 {
+		return r
+	}
+	cost, tolerance := l.cost(n), l.cost(l.Burst())
+	for {
+		now := l.elapsed()
+		old := atomic.LoadInt64(&l.tat)
+		tat := old
+		if tat < now {
+			tat = now
+		}
+		if atomic.CompareAndSwapInt64(&l.tat, old, tat+cost) {
+			r.cost = cost
+			if allowAt := tat + cost - tolerance; allowAt > now {
+				r.delay = time.Duration(allowAt - now)
+			}
+			return r
+		}
+	}
+}
+
+// TryAcquire 在不需要等待时申请n个令牌并返回true，否则不申请并返回false
+func (l *Limiter) TryAcquire(n int64) bool {
+	if n <= 0 || l.GetRateLimit() <= 0 {
+		return true
+	}
+	cost, tolerance := l.cost(n), l.cost(l.Burst())
+	for {
+		now := l.elapsed()
+		old := atomic.LoadInt64(&l.tat)
+		tat := old
+		if tat < now {
+			tat = now
+		}
+		if tat+cost-tolerance > now {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&l.tat, old, tat+cost) {
+			return true
+		}
 	}
-	go self.run(quantity)
-	return self
 }
 
-func (self *Limiter) Assign(size int64) int64 {
-	self.cond.L.Lock()
-	for self.capacity == 0 {
-		self.cond.Wait()
+// Wait 申请n个令牌，令牌不足时等待，ctx被取消时归还令牌并返回ctx.Err()
+func (l *Limiter) Wait(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	if size > self.capacity {
-		size = self.capacity
+	r := l.Reserve(n)
+	if r.delay <= 0 {
+		return nil
 	}
-	self.capacity -= size
-	self.cond.L.Unlock()
+	t := time.NewTimer(r.delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) Assign(size int64) int64 {
+	size, _ = l.AssignWithContext(context.Background(), size)
 	return size
 }
 
-func (self *Limiter) Fill(size int64) {
-	if size <= 0 {
+// AssignWithContext 等待size个令牌并返回size，ctx被取消时返回0和ctx.Err()
+func (l *Limiter) AssignWithContext(ctx context.Context, size int64) (int64, error) {
+	if err := l.Wait(ctx, size); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// Fill 归还size个令牌，桶中的令牌数不会超过burst
+func (l *Limiter) Fill(size int64) {
+	if size <= 0 || l.GetRateLimit() <= 0 {
 		return
 	}
+	l.refund(l.cost(size))
+}
 
-	self.cond.L.Lock()
-	self.capacity += size
-	if self.capacity > self.threshold {
-		self.capacity = self.threshold
+// refund 把tat提前cost纳秒，但是不早于now-tolerance，即桶中的令牌数不超过burst
+func (l *Limiter) refund(cost int64) {
+	tolerance := l.cost(l.Burst())
+	for {
+		floor := l.elapsed() - tolerance
+		old := atomic.LoadInt64(&l.tat)
+		if old <= floor {
+			return
+		}
+		tat := old - cost
+		if tat < floor {
+			tat = floor
+		}
+		if atomic.CompareAndSwapInt64(&l.tat, old, tat) {
+			return
+		}
 	}
-	self.cond.L.Unlock()
-	self.cond.Broadcast()
 }
 
-func (self *Limiter) run(quantity int64) {
-	t := time.NewTicker(Window)
+// SetRate 修改每秒产生的令牌数，桶中的令牌数和已经预留未到期的令牌数保持不变，小于等于0时不限速
+func (l *Limiter) SetRate(ratePerSecond int64) {
+	l.rescale(atomic.SwapInt64(&l.rate, ratePerSecond), ratePerSecond)
+}
+
+// rescale 把tat超出当前时间的部分从按旧速率计算换算为按新速率计算
+func (l *Limiter) rescale(old, ratePerSecond int64) {
+	if old <= 0 || ratePerSecond <= 0 || old == ratePerSecond {
+		return
+	}
 	for {
-		select {
-		case <-t.C:
-			self.cond.L.Lock()
-			self.capacity += quantity
-			if self.capacity >= self.threshold {
-				self.capacity = self.threshold
-			}
-			self.cond.L.Unlock()
-			self.cond.Broadcast()
-		case <-self.done:
-			t.Stop()
-			// 认为Close一定发生在所有Read之后
+		now := l.elapsed()
+		tat := atomic.LoadInt64(&l.tat)
+		if tat <= now {
+			return
+		}
+		debt := float64(tat-now) * float64(old) / float64(ratePerSecond)
+		if atomic.CompareAndSwapInt64(&l.tat, tat, now+int64(math.Min(debt, math.MaxInt64/4))) {
 			return
 		}
 	}
 }
 
-func (self *Limiter) Close() error {
-	self.done <- struct{}{}
+// SetBurst 修改桶的容量，小于1时为1
+func (l *Limiter) SetBurst(burst int64) {
+	if burst < 1 {
+		burst = 1
+	}
+	atomic.StoreInt64(&l.burst, burst)
+}
+
+func (l *Limiter) Burst() int64 {
+	return atomic.LoadInt64(&l.burst)
+}
+
+func (l *Limiter) GetRateLimit() int64 {
+	return atomic.LoadInt64(&l.rate)
+}
+
+// Close 保留用于兼容，Limiter没有需要释放的资源
+func (l *Limiter) Close() error {
 	return nil
 }
 
-func (self *Limiter) GetRateLimit() int64 {
-	return self.ratePerSecond
+func (l *Limiter) elapsed() int64 {
+	if l.now != nil {
+		return int64(l.now().Sub(l.start))
+	}
+	return int64(time.Since(l.start))
+}
+
+// cost 返回按当前速率产生n个令牌需要的纳秒数
+func (l *Limiter) cost(n int64) int64 {
+	cost := float64(n) * float64(time.Second) / float64(l.GetRateLimit())
+	if cost > math.MaxInt64/2 {
+		return math.MaxInt64 / 2
+	}
+	return int64(math.Ceil(cost))
 }
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(rate, burst int64) (*Limiter, *time.Time) {
+	l := NewLimiterWithBurst(rate, burst)
+	now := l.start
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestTryAcquire(t *testing.T) {
+	l, now := newTestLimiter(10, 5)
+	for i := 0; i < 5; i++ {
+		if !l.TryAcquire(1) {
+			t.Fatalf("expect token %d from burst", i)
+		}
+	}
+	if l.TryAcquire(1) {
+		t.Fatal("expect bucket to be empty")
+	}
+	*now = now.Add(100 * time.Millisecond)
+	if !l.TryAcquire(1) || l.TryAcquire(1) {
+		t.Fatal("expect exactly one token after 100ms")
+	}
+	*now = now.Add(time.Hour)
+	if !l.TryAcquire(5) || l.TryAcquire(1) {
+		t.Fatal("expect tokens not to exceed burst")
+	}
+}
+
+func TestReserve(t *testing.T) {
+	l, now := newTestLimiter(10, 10)
+	if r := l.Reserve(10); r.Delay() != 0 {
+		t.Fatalf("expect no delay for a full bucket, got %v", r.Delay())
+	}
+	// 超过burst的申请按速率等待，而不是被拒绝
+	r := l.Reserve(25)
+	if r.Delay() != 2500*time.Millisecond {
+		t.Fatalf("expect delay 2.5s, got %v", r.Delay())
+	}
+	r.Cancel()
+	r.Cancel()
+	if r = l.Reserve(5); r.Delay() != 500*time.Millisecond {
+		t.Fatalf("expect canceled tokens to be returned, got delay %v", r.Delay())
+	}
+	*now = now.Add(500 * time.Millisecond)
+	if l.TryAcquire(1) {
+		t.Fatal("expect bucket to be empty")
+	}
+}
+
+func TestFillNotExceedBurst(t *testing.T) {
+	l, now := newTestLimiter(10, 10)
+	tolerance := l.cost(l.Burst())
+	r := l.Reserve(30)
+	l.Fill(100)
+	if tat := atomic.LoadInt64(&l.tat); tat != l.elapsed()-tolerance {
+		t.Fatalf("expect tat to be clamped to a full bucket, got %v", time.Duration(tat-l.elapsed()))
+	}
+	r.Cancel()
+	if tat := atomic.LoadInt64(&l.tat); tat != l.elapsed()-tolerance {
+		t.Fatalf("expect cancel not to exceed burst, got %v", time.Duration(tat-l.elapsed()))
+	}
+	if !l.TryAcquire(10) || l.TryAcquire(1) {
+		t.Fatal("expect exactly burst tokens after fill")
+	}
+	*now = now.Add(time.Second)
+	l.Fill(5)
+	if !l.TryAcquire(10) || l.TryAcquire(1) {
+		t.Fatal("expect fill on a full bucket to be ignored")
+	}
+}
+
+func TestSetRate(t *testing.T) {
+	l, now := newTestLimiter(10, 1)
+	l.TryAcquire(1)
+	l.SetRate(100)
+	*now = now.Add(10 * time.Millisecond)
+	if !l.TryAcquire(1) {
+		t.Fatal("expect new rate to take effect")
+	}
+	l.SetRate(0)
+	for i := 0; i < 100; i++ {
+		if !l.TryAcquire(1000) {
+			t.Fatal("expect no limit when rate is 0")
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	l := NewLimiterWithBurst(100, 1)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Wait(context.Background(), 2); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expect 20 tokens to take about 190ms, got %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, 100); err != context.DeadlineExceeded {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+}
+
+func TestAdaptive(t *testing.T) {
+	l, now := newTestLimiter(100, 100)
+	l.OnThrottle()
+	if l.GetRateLimit() != 100 {
+		t.Fatal("expect no adjustment without adaptive mode")
+	}
+	l.SetAdaptive(&AdaptiveConfig{MinRate: 20, Increase: 5})
+	if c := l.Adaptive(); c.MaxRate != 100 || c.DecreaseFactor != defaultDecreaseFactor || c.Cooldown != defaultCooldown {
+		t.Fatalf("unexpected adaptive config %+v", c)
+	}
+
+	if !l.OnThrottle() || l.GetRateLimit() != 50 {
+		t.Fatalf("expect rate to be halved, got %d", l.GetRateLimit())
+	}
+	// 同一批失败的请求在Cooldown之内只降速一次
+	if l.OnThrottle() || l.GetRateLimit() != 50 {
+		t.Fatalf("expect no decrease within cooldown, got %d", l.GetRateLimit())
+	}
+	// 降速之后空闲一段时间，积累的令牌也不超过降低之后的burst
+	if l.Burst() != 50 {
+		t.Fatalf("expect burst to be scaled with rate, got %d", l.Burst())
+	}
+	*now = now.Add(time.Minute)
+	if !l.TryAcquire(50) || l.TryAcquire(1) {
+		t.Fatal("expect at most 50 tokens after idle")
+	}
+	for i := 0; i < 3; i++ {
+		*now = now.Add(time.Second)
+		l.OnThrottle()
+	}
+	if l.GetRateLimit() != 20 {
+		t.Fatalf("expect rate not to be lower than min rate, got %d", l.GetRateLimit())
+	}
+	for i := 0; i < 100; i++ {
+		l.OnSuccess()
+	}
+	if l.GetRateLimit() != 100 || l.Burst() != 100 {
+		t.Fatalf("expect rate and burst not to be higher than max, got %d/%d", l.GetRateLimit(), l.Burst())
+	}
+
+	l.SetAdaptive(nil)
+	*now = now.Add(time.Second)
+	if l.OnThrottle() || l.GetRateLimit() != 100 {
+		t.Fatal("expect no adjustment after adaptive mode disabled")
+	}
+}
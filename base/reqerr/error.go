@@ -1,7 +1,9 @@
 package reqerr
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 )
 
 const (
@@ -42,8 +44,140 @@ const (
 	ExecuteSqlError
 	EntityTooLargeError
 	InvalidDataSchemaError
+	SchemaConflictError
 )
 
+// 与ErrorType一一对应的错误，可以通过errors.Is(err, reqerr.ErrNoSuchRepo)判断错误类型，
+// pipeline、logdb和tsdb返回的错误都适用
+var (
+	ErrInvalidArgs              = errors.New("invalid args")
+	ErrNoSuchRepo               = errors.New("no such repo")
+	ErrRepoAlreadyExists        = errors.New("repo already exists")
+	ErrInvalidSliceArgument     = errors.New("invalid slice argument")
+	ErrUnmatchedSchema          = errors.New("unmatched schema")
+	ErrUnauthorized             = errors.New("unauthorized")
+	ErrInternalServer           = errors.New("internal server error")
+	ErrNoSuchGroup              = errors.New("no such group")
+	ErrGroupAlreadyExists       = errors.New("group already exists")
+	ErrNoSuchTransform          = errors.New("no such transform")
+	ErrTransformAlreadyExists   = errors.New("transform already exists")
+	ErrNoSuchExport             = errors.New("no such export")
+	ErrExportAlreadyExists      = errors.New("export already exists")
+	ErrNoSuchPlugin             = errors.New("no such plugin")
+	ErrPluginAlreadyExists      = errors.New("plugin already exists")
+	ErrRepoCascading            = errors.New("repo cascading")
+	ErrRepoInCreating           = errors.New("repo in creating")
+	ErrInvalidTransformSpec     = errors.New("invalid transform spec")
+	ErrInvalidExportSpec        = errors.New("invalid export spec")
+	ErrNoSuchRetention          = errors.New("no such retention")
+	ErrSeriesAlreadyExists      = errors.New("series already exists")
+	ErrNoSuchSeries             = errors.New("no such series")
+	ErrInvalidSeriesName        = errors.New("invalid series name")
+	ErrInvalidViewName          = errors.New("invalid view name")
+	ErrInvalidViewSql           = errors.New("invalid view sql")
+	ErrViewFuncNotSupport       = errors.New("view func not support")
+	ErrNoSuchView               = errors.New("no such view")
+	ErrViewAlreadyExists        = errors.New("view already exists")
+	ErrInvalidViewStatement     = errors.New("invalid view statement")
+	ErrPointsNotInSameRetention = errors.New("points not in same retention")
+	ErrTimestampTooFarFromNow   = errors.New("timestamp too far from now")
+	ErrInvalidQuerySql          = errors.New("invalid query sql")
+	ErrQueryInterrupt           = errors.New("query interrupt")
+	ErrExecuteSql               = errors.New("execute sql failed")
+	ErrEntityTooLarge           = errors.New("entity too large")
+	ErrInvalidDataSchema        = errors.New("invalid data schema")
+	ErrSchemaConflict           = errors.New("schema conflict")
+)
+
+var sentinels = map[int]error{
+	InvalidArgs:                   ErrInvalidArgs,
+	NoSuchRepoError:               ErrNoSuchRepo,
+	RepoAlreadyExistsError:        ErrRepoAlreadyExists,
+	InvalidSliceArgumentError:     ErrInvalidSliceArgument,
+	UnmatchedSchemaError:          ErrUnmatchedSchema,
+	UnauthorizedError:             ErrUnauthorized,
+	InternalServerError:           ErrInternalServer,
+	NoSuchGroupError:              ErrNoSuchGroup,
+	GroupAlreadyExistsError:       ErrGroupAlreadyExists,
+	NoSuchTransformError:          ErrNoSuchTransform,
+	TransformAlreadyExistsError:   ErrTransformAlreadyExists,
+	NoSuchExportError:             ErrNoSuchExport,
+	ExportAlreadyExistsError:      ErrExportAlreadyExists,
+	NoSuchPluginError:             ErrNoSuchPlugin,
+	PluginAlreadyExistsError:      ErrPluginAlreadyExists,
+	RepoCascadingError:            ErrRepoCascading,
+	RepoInCreatingError:           ErrRepoInCreating,
+	InvalidTransformSpecError:     ErrInvalidTransformSpec,
+	InvalidExportSpecError:        ErrInvalidExportSpec,
+	NoSuchRetentionError:          ErrNoSuchRetention,
+	SeriesAlreadyExistsError:      ErrSeriesAlreadyExists,
+	NoSuchSeriesError:             ErrNoSuchSeries,
+	InvalidSeriesNameError:        ErrInvalidSeriesName,
+	InvalidViewNameError:          ErrInvalidViewName,
+	InvalidViewSqlError:           ErrInvalidViewSql,
+	ViewFuncNotSupportError:       ErrViewFuncNotSupport,
+	NoSuchViewError:               ErrNoSuchView,
+	ViewAlreadyExistsError:        ErrViewAlreadyExists,
+	InvalidViewStatementError:     ErrInvalidViewStatement,
+	PointsNotInSameRetentionError: ErrPointsNotInSameRetention,
+	TimestampTooFarFromNowError:   ErrTimestampTooFarFromNow,
+	InvalidQuerySql:               ErrInvalidQuerySql,
+	QueryInterruptError:           ErrQueryInterrupt,
+	ExecuteSqlError:               ErrExecuteSql,
+	EntityTooLargeError:           ErrEntityTooLarge,
+	InvalidDataSchemaError:        ErrInvalidDataSchema,
+	SchemaConflictError:           ErrSchemaConflict,
+}
+
+var errorTypeNames = []string{
+	"DefaultRequestError",
+	"InvalidArgs",
+	"NoSuchRepoError",
+	"RepoAlreadyExistsError",
+	"InvalidSliceArgumentError",
+	"UnmatchedSchemaError",
+	"UnauthorizedError",
+	"InternalServerError",
+	"NoSuchGroupError",
+	"GroupAlreadyExistsError",
+	"NoSuchTransformError",
+	"TransformAlreadyExistsError",
+	"NoSuchExportError",
+	"ExportAlreadyExistsError",
+	"NoSuchPluginError",
+	"PluginAlreadyExistsError",
+	"RepoCascadingError",
+	"RepoInCreatingError",
+	"InvalidTransformSpecError",
+	"InvalidExportSpecError",
+	"NoSuchRetentionError",
+	"SeriesAlreadyExistsError",
+	"NoSuchSeriesError",
+	"InvalidSeriesNameError",
+	"InvalidViewNameError",
+	"InvalidViewSqlError",
+	"ViewFuncNotSupportError",
+	"NoSuchViewError",
+	"ViewAlreadyExistsError",
+	"InvalidViewStatementError",
+	"PointsNotInSameRetentionError",
+	"TimestampTooFarFromNowError",
+	"InvalidQuerySql",
+	"QueryInterruptError",
+	"ExecuteSqlError",
+	"EntityTooLargeError",
+	"InvalidDataSchemaError",
+	"SchemaConflictError",
+}
+
+// ErrorTypeName 返回ErrorType对应的常量名，例如NoSuchRepoError，未知的类型返回"UnknownError"
+func ErrorTypeName(errorType int) string {
+	if errorType < 0 || errorType >= len(errorTypeNames) {
+		return "UnknownError"
+	}
+	return errorTypeNames[errorType]
+}
+
 type ErrBuilder interface {
 	Build(message, rawText, reqId string, statusCode int) error
 }
@@ -55,14 +189,26 @@ func NewInvalidArgs(name, message string) *RequestError {
 	}
 }
 
+// NewSchemaConflict 表示已存在的repo与期望的schema不兼容，无法通过更新repo解决
+func NewSchemaConflict(repoName, message string) *RequestError {
+	return &RequestError{
+		Message:   fmt.Sprintf("Schema conflict, repoName: %s, reason: %s", repoName, message),
+		ErrorType: SchemaConflictError,
+	}
+}
+
 type RequestError struct {
 	Message    string `json:"error"`
 	StatusCode int    `json:"-"`
 	RequestId  string `json:"-"`
 	RawMessage string `json:"-"`
 	ErrorType  int    `json:"-"`
+	// ErrorCode 是服务端返回的错误码，例如E18102、E8111、E7100，没有错误码时为空
+	ErrorCode string `json:"-"`
 }
 
+var errorCodePattern = regexp.MustCompile(`^E[0-9]+`)
+
 func New(message, rawText, reqId string, statusCode int) *RequestError {
 	return &RequestError{
 		Message:    message,
@@ -70,9 +216,24 @@ func New(message, rawText, reqId string, statusCode int) *RequestError {
 		RequestId:  reqId,
 		RawMessage: rawText,
 		ErrorType:  DefaultRequestError,
+		ErrorCode:  errorCodePattern.FindString(message),
 	}
 }
 
 func (r RequestError) Error() string {
 	return fmt.Sprintf("pandora error: StatusCode=%d, ErrorMessage=%s, RequestId=%s", r.StatusCode, r.Message, r.RequestId)
 }
+
+// Is 判断错误是否对应target表示的错误类型，target可以是ErrNoSuchRepo等错误，
+// 也可以是另一个RequestError，此时比较两者的ErrorType
+func (r RequestError) Is(target error) bool {
+	if t, ok := target.(*RequestError); ok {
+		return t != nil && t.ErrorType == r.ErrorType
+	}
+	return target != nil && sentinels[r.ErrorType] == target
+}
+
+// Unwrap 返回ErrorType对应的错误，DefaultRequestError返回nil
+func (r RequestError) Unwrap() error {
+	return sentinels[r.ErrorType]
+}
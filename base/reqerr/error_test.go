@@ -0,0 +1,78 @@
+package reqerr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		message string
+		code    string
+	}{
+		{"E18102: repo not found", "E18102"},
+		{"E8111 repo not found", "E8111"},
+		{"E7100", "E7100"},
+		{"unauthorized", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := New(tt.message, "", "", 400).ErrorCode; got != tt.code {
+			t.Errorf("message %q, expect code %q, got %q", tt.message, tt.code, got)
+		}
+	}
+}
+
+func TestErrorsIs(t *testing.T) {
+	err := New("E18102: repo not found", "", "", 404)
+	err.ErrorType = NoSuchRepoError
+
+	if !errors.Is(err, ErrNoSuchRepo) {
+		t.Error("expect ErrNoSuchRepo")
+	}
+	if errors.Is(err, ErrRepoAlreadyExists) {
+		t.Error("unexpected ErrRepoAlreadyExists")
+	}
+	if !errors.Is(fmt.Errorf("create repo: %w", error(err)), ErrNoSuchRepo) {
+		t.Error("expect ErrNoSuchRepo through wrapped error")
+	}
+	if !errors.Is(err, &RequestError{ErrorType: NoSuchRepoError}) {
+		t.Error("expect match on ErrorType")
+	}
+	if !errors.Is(NewInvalidArgs("RepoName", "empty"), ErrInvalidArgs) {
+		t.Error("expect ErrInvalidArgs")
+	}
+	if errors.Unwrap(New("unknown", "", "", 500)) != nil {
+		t.Error("default request error should not unwrap")
+	}
+
+	var reqErr *RequestError
+	if !errors.As(fmt.Errorf("wrapped: %w", error(err)), &reqErr) || reqErr.ErrorCode != "E18102" {
+		t.Errorf("expect RequestError with code E18102, got %v", reqErr)
+	}
+
+	// RequestError的值也实现了error，并且可以匹配对应的错误
+	var value error = *err
+	if value.Error() != err.Error() {
+		t.Errorf("expect value receiver Error, got %s", value.Error())
+	}
+	if !errors.Is(value, ErrNoSuchRepo) || !errors.Is(fmt.Errorf("wrapped: %w", value), ErrNoSuchRepo) {
+		t.Error("expect ErrNoSuchRepo from RequestError value")
+	}
+	if !errors.Is(value, &RequestError{ErrorType: NoSuchRepoError}) {
+		t.Error("expect RequestError value to match on ErrorType")
+	}
+}
+
+func TestErrorTypeName(t *testing.T) {
+	if len(errorTypeNames) != SchemaConflictError+1 {
+		t.Fatalf("expect %d error type names, got %d", SchemaConflictError+1, len(errorTypeNames))
+	}
+	if got := ErrorTypeName(NoSuchRepoError); got != "NoSuchRepoError" {
+		t.Errorf("expect NoSuchRepoError, got %s", got)
+	}
+	if got := ErrorTypeName(SchemaConflictError + 1); got != "UnknownError" {
+		t.Errorf("expect UnknownError, got %s", got)
+	}
+}
@@ -3,6 +3,7 @@ package request
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
@@ -35,18 +36,20 @@ type Request struct {
 	Headers          map[string]string
 	EnableContentMD5 bool
 	Logger           base.Logger
+	ctx              context.Context
 	token            string
+	creds            base.Credentials
 	bodyLength       int64
 	errBuilder       reqerr.ErrBuilder
 	reqlimiter       *ratelimit.Limiter
 	flowlimiter      *ratelimit.Limiter
+	limitWait        time.Duration
+	clockAdjusted    bool //最近一次响应校正了本地时间的偏差
+	resigned         bool
+	operation        *Operation //创建请求时的Operation，拦截器每次尝试拿到的都是它的副本
 }
 
-type Operation struct {
-	Name   string
-	Method string
-	Path   string
-}
+type Operation = base.Operation
 
 func New(cfg *config.Config, client *http.Client, op *Operation, token string, errBuilder reqerr.ErrBuilder, data interface{}) *Request {
 	httpReq, _ := http.NewRequest(op.Method, "", nil)
@@ -76,6 +79,7 @@ func New(cfg *config.Config, client *http.Client, op *Operation, token string, e
 		Logger:      logger,
 		token:       token,
 		errBuilder:  errBuilder,
+		operation:   op,
 	}
 
 	return r
@@ -157,11 +161,11 @@ func (r *Request) SetVariantBody(v interface{}) error {
 	vv, ok := v.(base.Validator)
 	if !ok {
 		r.Error = fmt.Errorf("invalid type cast, cannot cast to validator")
-		r.Logger.Error(logFormatter(r, "cast to validator"))
+		r.logError("cast to validator")
 		return r.Error
 	}
 	if r.Error = vv.Validate(); r.Error != nil {
-		r.Logger.Error(logFormatter(r, "validate input"))
+		r.logError("validate input")
 		return r.Error
 	}
 
@@ -172,21 +176,39 @@ func (r *Request) SetVariantBody(v interface{}) error {
 	return nil
 }
 
+// gzipOperations 是开启Config.Gzip之后会压缩请求体的写入操作
+var gzipOperations = map[string]bool{
+	base.OpPostData:    true,
+	base.OpSendLog:     true,
+	base.OpWritePoints: true,
+}
+
+func (r *Request) gzipEnabled() bool {
+	return r.Config.Gzip && gzipOperations[r.Operation.Name]
+}
+
+func gzipReader(reader io.Reader) (*bytes.Reader, error) {
+	var buf bytes.Buffer
+	g := gzip.NewWriter(&buf)
+	if _, err := io.Copy(g, reader); err != nil {
+		return nil, err
+	}
+	if err := g.Close(); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
 func (r *Request) SetReaderBody(reader io.ReadSeeker) (err error) {
 	reader.Seek(0, 0)
-	if r.Config.Gzip && r.Operation.Name == base.OpPostData {
-		var buf bytes.Buffer
-		g := gzip.NewWriter(&buf)
-		_, err = io.Copy(g, reader)
-		if err != nil {
-			return
-		}
-		if err = g.Close(); err != nil {
+	if r.gzipEnabled() {
+		var gz *bytes.Reader
+		if gz, err = gzipReader(reader); err != nil {
 			return
 		}
 		r.SetHeader("Content-Encoding", "gzip")
-		r.bodyLength = int64(buf.Len())
-		reader = bytes.NewReader(buf.Bytes())
+		r.bodyLength = int64(gz.Len())
+		reader = gz
 	}
 	r.HTTPRequest.Body = newOffsetReader(reader, 0)
 	r.Body = reader
@@ -205,6 +227,22 @@ func (r *Request) SetBodyLength(bodyLength int64) {
 	r.bodyLength = bodyLength
 }
 
+// SetContext 设置请求的context，context被取消或超时后，限速等待和HTTP请求都会被中断
+func (r *Request) SetContext(ctx context.Context) {
+	if ctx == nil {
+		panic("context must not be nil")
+	}
+	r.ctx = ctx
+	r.HTTPRequest = r.HTTPRequest.WithContext(ctx)
+}
+
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
 func (r *Request) SetReqLimiter(limiter *ratelimit.Limiter) {
 	r.reqlimiter = limiter
 }
@@ -218,58 +256,105 @@ func (r *Request) build() {
 		r.HTTPRequest.Header.Set(k, v)
 	}
 
-	r.HTTPRequest.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
-
-	r.handleBody()
-	if r.Error != nil {
-		return
+	if r.token == "" {
+		if r.creds, r.Error = r.Config.RetrieveCredentials(); r.Error != nil {
+			return
+		}
 	}
+	r.handleBody()
+}
+
+func (r *Request) sign() {
+	r.HTTPRequest.Header.Set("Date", r.Config.Clock.Now().UTC().Format(http.TimeFormat))
 
 	if r.token != "" {
 		r.HTTPRequest.Header.Set("Authorization", r.token)
 		return
 	}
 
-	r.Error = base.Sign(r.Config.Ak, r.Config.Sk, r.HTTPRequest)
-	if r.Error != nil {
-		return
-	}
+	r.Error = base.Sign(r.creds.AccessKey, r.creds.SecretKey, r.HTTPRequest)
 }
 
 func (r *Request) Send() error {
 	r.build()
 	if r.Error != nil {
-		r.Logger.Error(logFormatter(r, "build request"))
+		r.logError("build request")
 		return r.Error
 	}
-	if r.reqlimiter != nil {
-		r.reqlimiter.Assign(1)
+
+	tracer := r.Config.Tracer
+	if tracer == nil {
+		tracer = base.NoopTracer{}
 	}
-	if r.flowlimiter != nil {
-		bandneed := r.bodyLength
-		if bandneed > r.flowlimiter.GetRateLimit() {
-			r.Error = r.errBuilder.Build("E18005",
-				fmt.Sprintf("can not send request, as body size %v larger than flow rate limit %v", bandneed, r.flowlimiter.GetRateLimit()),
-				"NOTSENDYET", 400)
-			r.Logger.Error(logFormatter(r, "flow rate limit"))
-			return r.Error
+	ctx, span := tracer.Start(r.Context(), r.Operation.Name)
+	if ctx != r.Context() {
+		r.SetContext(ctx)
+	}
+	tracer.Inject(ctx, r.HTTPRequest.Header)
+	attempts := r.sendWithRetry()
+	r.endSpan(span, attempts)
+	return r.Error
+}
+
+// sendWithRetry 按照Config.RetryPolicy发送请求，返回尝试的次数
+func (r *Request) sendWithRetry() int {
+	policy := r.Config.RetryPolicy
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		r.limitWait = 0
+		r.intercept(attempt)
+		latency := time.Since(start)
+		r.logAttempt(attempt, latency)
+		r.adaptRateLimit(latency - r.limitWait)
+		r.observe(attempt, latency)
+		if r.resignAfterClockSkew() {
+			continue
+		}
+		if r.Error == nil || !r.shouldRetry(policy, attempt) {
+			return attempt
 		}
-		for bandneed > 0 {
-			ret := r.flowlimiter.Assign(bandneed)
-			bandneed -= ret
+		delay := policy.Backoff(attempt)
+		base.LogFields(r.Logger, base.LogWarn, "retry request",
+			base.F("op", r.Operation.Name),
+			base.F("attempt", attempt),
+			base.F("delay", delay),
+			base.F("error", r.Error))
+		if err := r.sleep(delay); err != nil {
+			r.Error = err
+			return attempt
 		}
+		r.rewindBody()
+	}
+}
+
+func (r *Request) send() {
+	r.HTTPResponse, r.Error = nil, nil
+	// 先等待限速，避免等待之后发送的Date和签名已经过期
+	waitStart := time.Now()
+	r.waitRateLimit()
+	r.limitWait = time.Since(waitStart)
+	if r.Error != nil {
+		return
+	}
+	r.sign()
+	if r.Error != nil {
+		r.logError("sign request")
+		return
 	}
 
+	sent := time.Now()
+	r.clockAdjusted = false
 	r.HTTPResponse, r.Error = r.HTTPClient.Do(r.HTTPRequest)
 	if r.Error != nil {
-		r.Logger.Error(logFormatter(r, "send request"))
-		return r.Error
+		r.logError("send request")
+		return
 	}
+	r.observeClock(sent, time.Now())
 
 	buf := r.readResponse()
 	if r.Error != nil {
-		r.Logger.Error(logFormatter(r, "read response"))
-		return r.Error
+		r.logError("read response")
+		return
 	}
 	if r.HTTPResponse.StatusCode == 200 {
 		r.unmarshal(buf)
@@ -279,12 +364,185 @@ func (r *Request) Send() error {
 				string(buf),
 				r.HTTPResponse.Header.Get(base.HTTPHeaderRequestId),
 				r.HTTPResponse.StatusCode)
-			r.Logger.Error(logFormatter(r, "receive non-json response"))
-			return r.Error
+			r.logError("receive non-json response")
+			return
 		}
 		r.unmarshalError(buf)
 	}
-	return r.Error
+}
+
+// waitRateLimit 在RequestRateLimit和FlowRateLimit限速器上等待发送额度，
+// 超过每秒流量限制的请求体会等待多秒的额度之后再发送
+func (r *Request) waitRateLimit() {
+	if r.reqlimiter != nil {
+		if r.Error = r.reqlimiter.Wait(r.Context(), 1); r.Error != nil {
+			r.logError("request rate limit")
+			return
+		}
+	}
+	if r.flowlimiter != nil && r.bodyLength > 0 {
+		if r.Error = r.flowlimiter.Wait(r.Context(), r.bodyLength); r.Error != nil {
+			r.logError("flow rate limit")
+			return
+		}
+	}
+}
+
+// intercept 通过Config.Interceptors发送一次请求，没有配置拦截器时直接发送
+func (r *Request) intercept(attempt int) {
+	if len(r.Config.Interceptors) == 0 {
+		r.send()
+		return
+	}
+	r.HTTPResponse, r.Error = nil, nil
+	op := *r.operation
+	call := &base.Call{
+		Context:     r.Context(),
+		Operation:   &op,
+		Attempt:     attempt,
+		HTTPRequest: r.HTTPRequest,
+		Body:        r.Body,
+	}
+	r.Error = base.Chain(r.Config.Interceptors, func(call *base.Call) error {
+		if r.applyCall(call); r.Error != nil {
+			call.Error = r.Error
+			return r.Error
+		}
+		start := time.Now()
+		r.send()
+		call.HTTPResponse, call.Error, call.Latency = r.HTTPResponse, r.Error, time.Since(start)
+		return r.Error
+	})(call)
+}
+
+// applyCall 把拦截器对Operation、HTTPRequest和Body的修改应用到请求上，
+// 替换的Body应为未压缩的数据，与SetReaderBody一样按Config.Gzip压缩
+func (r *Request) applyCall(call *base.Call) {
+	if call.HTTPRequest != nil && call.HTTPRequest != r.HTTPRequest {
+		r.HTTPRequest = call.HTTPRequest
+	}
+	if call.Operation != nil {
+		r.Operation = call.Operation
+		r.HTTPRequest.Method = call.Operation.Method
+		if u := r.Config.Endpoint + call.Operation.Path; u != r.HTTPRequest.URL.String() {
+			if r.HTTPRequest.URL, r.Error = url.Parse(u); r.Error != nil {
+				return
+			}
+			r.HTTPRequest.Host = r.HTTPRequest.URL.Host
+		}
+	}
+	if call.Body != r.Body {
+		r.Body = call.Body
+		r.HTTPRequest.Body, r.HTTPRequest.ContentLength = nil, 0
+		if r.Body != nil {
+			if _, r.Error = r.Body.Seek(0, 0); r.Error != nil {
+				return
+			}
+			if r.gzipEnabled() {
+				var gz *bytes.Reader
+				if gz, r.Error = gzipReader(r.Body); r.Error != nil {
+					return
+				}
+				r.Body = gz
+				r.HTTPRequest.Header.Set("Content-Encoding", "gzip")
+			} else if r.Headers["Content-Encoding"] == "gzip" {
+				r.HTTPRequest.Header.Del("Content-Encoding")
+			}
+			r.HTTPRequest.Body = newOffsetReader(r.Body, 0)
+			r.handleBody()
+		}
+		r.bodyLength = r.HTTPRequest.ContentLength
+	}
+}
+
+// 以下操作虽然使用POST方法，但不会修改服务端的状态，可以安全的重试
+var readOnlyPostOps = map[string]bool{
+	base.OpQueryPoints:     true,
+	base.OpRetrieveSchema:  true,
+	base.OpVerifyTransform: true,
+	base.OpVerifyExport:    true,
+}
+
+func (r *Request) idempotent() bool {
+	return r.Operation.Method != base.MethodPost || readOnlyPostOps[r.Operation.Name]
+}
+
+func (r *Request) shouldRetry(policy *config.RetryPolicy, attempt int) bool {
+	if policy == nil || attempt >= policy.MaxAttempts {
+		return false
+	}
+	if r.Context().Err() != nil {
+		return false
+	}
+	if !r.idempotent() && !policy.RetryNonIdempotent {
+		return false
+	}
+	if r.Body != nil {
+		if _, ok := r.HTTPRequest.Body.(*offsetReader); !ok {
+			return false
+		}
+	}
+	if reqErr, ok := r.Error.(*reqerr.RequestError); ok {
+		return policy.IsRetryable(reqErr)
+	}
+	// 没有拿到响应，说明是建立连接或者传输过程中的错误
+	return r.HTTPResponse == nil
+}
+
+// observeClock 根据响应的Date头更新Config.Clock，偏差较大时输出警告
+func (r *Request) observeClock(sent, received time.Time) {
+	date, err := http.ParseTime(r.HTTPResponse.Header.Get("Date"))
+	if err != nil || r.Config.Clock == nil {
+		return
+	}
+	offset, changed := r.Config.Clock.Observe(date, sent, received)
+	if !changed {
+		return
+	}
+	r.clockAdjusted = true
+	level := base.LogInfo
+	if offset >= base.ClockSkewWarnThreshold || offset <= -base.ClockSkewWarnThreshold {
+		level = base.LogWarn
+	}
+	base.LogFields(r.Logger, level, "local clock is out of sync with server, signing with corrected time",
+		base.F("op", r.Operation.Name),
+		base.F("offset", offset))
+}
+
+// resignAfterClockSkew 判断请求是否因为本地时间的偏差被拒绝，此时使用校正之后的时间重新签名并发送一次，
+// 即使没有设置RetryPolicy
+func (r *Request) resignAfterClockSkew() bool {
+	if r.Error == nil || !r.clockAdjusted || r.resigned || r.token != "" {
+		return false
+	}
+	if r.HTTPResponse == nil || r.HTTPResponse.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	if r.Body != nil {
+		if _, ok := r.HTTPRequest.Body.(*offsetReader); !ok {
+			return false
+		}
+	}
+	r.resigned = true
+	r.rewindBody()
+	return true
+}
+
+func (r *Request) sleep(d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
+}
+
+func (r *Request) rewindBody() {
+	if reader, ok := r.HTTPRequest.Body.(*offsetReader); ok {
+		r.HTTPRequest.Body = reader.CloseAndCopy(0)
+	}
 }
 
 func (r *Request) unmarshal(buf []byte) {
@@ -295,7 +553,7 @@ func (r *Request) unmarshal(buf []byte) {
 	}
 	r.Error = json.Unmarshal(buf, &r.Data)
 	if r.Error != nil {
-		r.Logger.Error(logFormatter(r, fmt.Sprintf("unmarshal response: %s", string(buf))))
+		r.logError(fmt.Sprintf("unmarshal response: %s", string(buf)))
 		return
 	}
 }
@@ -306,7 +564,7 @@ func (r *Request) unmarshalError(buf []byte) {
 		err1 := json.Unmarshal(buf, &err)
 		if err1 != nil {
 			r.Error = err1
-			r.Logger.Error(logFormatter(r, "unmarshal error"))
+			r.logError("unmarshal error")
 			return
 		}
 	} else {
@@ -328,6 +586,135 @@ func (r *Request) readResponse() (out []byte) {
 	return
 }
 
+// logAttempt 在每次发送请求之后输出一条debug日志
+func (r *Request) logAttempt(attempt int, duration time.Duration) {
+	var status int
+	var reqId string
+	if r.HTTPResponse != nil {
+		status = r.HTTPResponse.StatusCode
+		reqId = r.HTTPResponse.Header.Get(base.HTTPHeaderRequestId)
+	}
+	fields := []base.Field{
+		base.F("op", r.Operation.Name),
+		base.F("method", r.Operation.Method),
+		base.F("path", r.HTTPRequest.URL.Path),
+		base.F("status", status),
+		base.F("reqid", reqId),
+		base.F("body_size", r.bodySize()),
+		base.F("duration", duration),
+		base.F("attempt", attempt),
+	}
+	if r.Error != nil {
+		fields = append(fields, base.F("error", r.Error))
+	}
+	base.LogFields(r.Logger, base.LogDebug, "send request", fields...)
+}
+
+// observe 把一次请求尝试的统计信息上报给Config.Metrics
+func (r *Request) observe(attempt int, latency time.Duration) {
+	if r.Config.Metrics == nil {
+		return
+	}
+	stats := &base.RequestStats{
+		Operation:     r.Operation.Name,
+		Attempt:       attempt,
+		Error:         r.Error,
+		Latency:       latency,
+		RateLimitWait: r.limitWait,
+	}
+	if r.reqlimiter != nil {
+		stats.RequestRateLimit = r.reqlimiter.GetRateLimit()
+	}
+	if r.flowlimiter != nil {
+		stats.FlowRateLimit = r.flowlimiter.GetRateLimit()
+	}
+	if r.HTTPResponse != nil {
+		stats.StatusCode = r.HTTPResponse.StatusCode
+		stats.BytesSent = r.bodySize()
+	}
+	r.Config.Metrics.ObserveRequest(stats)
+}
+
+// adaptRateLimit 根据响应的状态码和耗时调整自适应限速器的速率，没有收到响应时不做调整
+func (r *Request) adaptRateLimit(latency time.Duration) {
+	a := r.Config.AdaptiveRateLimit
+	if a == nil || r.HTTPResponse == nil {
+		return
+	}
+	throttled := a.IsThrottled(r.HTTPResponse.StatusCode, latency)
+	for _, l := range []*ratelimit.Limiter{r.reqlimiter, r.flowlimiter} {
+		if l == nil {
+			continue
+		}
+		if !throttled {
+			if r.Error == nil {
+				l.OnSuccess()
+			}
+			continue
+		}
+		if l.OnThrottle() {
+			base.LogFields(r.Logger, base.LogWarn, "decrease rate limit",
+				base.F("op", r.Operation.Name),
+				base.F("status", r.HTTPResponse.StatusCode),
+				base.F("latency", latency),
+				base.F("rate", l.GetRateLimit()))
+		}
+	}
+}
+
+func (r *Request) bodySize() int64 {
+	if r.HTTPRequest.ContentLength > 0 {
+		return r.HTTPRequest.ContentLength
+	}
+	return r.bodyLength
+}
+
+// endSpan 记录请求的结果并结束span，status和reqid取自最后一次尝试
+func (r *Request) endSpan(span base.Span, attempts int) {
+	attrs := []base.Field{
+		base.F(base.AttrOperation, r.Operation.Name),
+		base.F(base.AttrHTTPMethod, r.HTTPRequest.Method),
+		base.F(base.AttrHTTPPath, r.HTTPRequest.URL.Path),
+		base.F(base.AttrAttempts, attempts),
+	}
+	if repo := repoName(r.HTTPRequest.URL.Path); repo != "" {
+		attrs = append(attrs, base.F(base.AttrRepo, repo))
+	}
+	if r.HTTPResponse != nil {
+		attrs = append(attrs,
+			base.F(base.AttrHTTPStatus, r.HTTPResponse.StatusCode),
+			base.F(base.AttrRequestId, r.HTTPResponse.Header.Get(base.HTTPHeaderRequestId)))
+	}
+	span.SetAttributes(attrs...)
+	if r.Error != nil {
+		span.SetError(r.Error)
+	}
+	span.End()
+}
+
+// repoName 从形如/v2/repos/<repo>/data的路径中取出repo名字
+func repoName(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "repos" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// logError 在Logger实现了base.StructuredLogger时输出带字段的日志，否则保持原有的格式
+func (r *Request) logError(stage string) {
+	if _, ok := r.Logger.(base.StructuredLogger); !ok {
+		r.Logger.Error(logFormatter(r, stage))
+		return
+	}
+	base.LogFields(r.Logger, base.LogError, stage+" failed",
+		base.F("op", r.Operation.Name),
+		base.F("path", r.HTTPRequest.URL.Path),
+		base.F("error", r.Error))
+}
+
 func logFormatter(r *Request, stage string) string {
 	return fmt.Sprintf("%s failed, operation %s, error %v",
 		stage, r.Operation.Name, r.Error)
@@ -1,12 +1,26 @@
 package request
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/qiniu/pandora-go-sdk/base"
 	"github.com/qiniu/pandora-go-sdk/base/config"
+	"github.com/qiniu/pandora-go-sdk/base/ratelimit"
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
 )
 
+var testLogger = &base.DefaultLogger{Logger: log.New(ioutil.Discard, "", 0)}
+
 func BenchmarkGzip(b *testing.B) {
 	req := New(&config.Config{Gzip: true}, nil, &Operation{Method: "POST"}, "", nil, nil)
 	var s string
@@ -32,3 +46,506 @@ func BenchmarkNoGzip(b *testing.B) {
 	}
 	//	fmt.Println(req.bodyLength)
 }
+
+func TestSendWithCanceledContext(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer ts.Close()
+
+	cfg := &config.Config{Endpoint: ts.URL, Logger: testLogger}
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpGetRepo, Method: "GET", Path: "/v2/repos/repo"}, "", nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req.SetContext(ctx)
+
+	err := req.Send()
+	if err == nil {
+		t.Fatal("send should fail after context deadline")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("context should be expired, got %v", ctx.Err())
+	}
+}
+
+func TestRateLimitWaitCanceled(t *testing.T) {
+	limiter := ratelimit.NewLimiter(1)
+	defer limiter.Close()
+	limiter.Assign(1)
+
+	cfg := &config.Config{Endpoint: "http://127.0.0.1:1", Logger: testLogger}
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpPostData, Method: "POST", Path: "/v2/repos/repo/data"}, "", nil, nil)
+	req.SetReqLimiter(limiter)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req.SetContext(ctx)
+
+	if err := req.Send(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("send should be canceled while waiting for rate limiter, got %v", err)
+	}
+}
+
+func TestFlowLimitLargeBody(t *testing.T) {
+	var bodies []string
+	ts := newFlakyServer(t, 0, &bodies)
+	defer ts.Close()
+
+	// body是每秒流量限制的1.5倍，扣除初始的额度之后需要等待0.5秒
+	limiter := ratelimit.NewLimiter(100)
+	var stats statsRecorder
+	cfg := (&config.Config{Endpoint: ts.URL, Logger: testLogger}).WithMetrics(&stats)
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpPostData, Method: "POST", Path: "/v2/repos/repo/data"}, "", testErrBuilder{}, nil)
+	req.SetFlowLimiter(limiter)
+	req.SetStringBody(strings.Repeat("a", 150))
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 1 || len(bodies[0]) != 150 {
+		t.Fatalf("expect body to be sent, got %v", bodies)
+	}
+	if len(stats) != 1 || stats[0].RateLimitWait < 400*time.Millisecond {
+		t.Fatalf("expect request to be paced for about 500ms, got %+v", stats)
+	}
+}
+
+func TestSignAfterRateLimit(t *testing.T) {
+	var date time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		date, _ = http.ParseTime(r.Header.Get("Date"))
+	}))
+	defer ts.Close()
+
+	// 需要等待1.2秒的额度，Date头只精确到秒
+	limiter := ratelimit.NewLimiterWithBurst(100, 1)
+	req := New(&config.Config{Endpoint: ts.URL, Logger: testLogger}, http.DefaultClient, &Operation{Name: base.OpPostData, Method: "POST", Path: "/v2/repos/repo/data"}, "", testErrBuilder{}, nil)
+	req.SetFlowLimiter(limiter)
+	req.SetStringBody(strings.Repeat("a", 121))
+	start := time.Now()
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if date.Before(start.Add(200 * time.Millisecond)) {
+		t.Fatalf("expect request to be signed after rate limit wait, date %v, start %v", date, start)
+	}
+}
+
+func TestAdaptiveRateLimit(t *testing.T) {
+	var status = http.StatusTooManyRequests
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	cfg := &config.Config{
+		Endpoint:          ts.URL,
+		Logger:            testLogger,
+		RequestRateLimit:  1000,
+		FlowRateLimit:     1024,
+		AdaptiveRateLimit: &config.AdaptiveRateLimit{MinFlowRate: 256},
+	}
+	reqLimit, flowLimit := cfg.NewRequestLimiter(), cfg.NewFlowLimiter()
+	var stats statsRecorder
+	cfg.Metrics = &stats
+	send := func() error {
+		req := New(cfg, http.DefaultClient, &Operation{Name: base.OpPostData, Method: "POST", Path: "/v2/repos/repo/data"}, "", testErrBuilder{}, nil)
+		req.SetReqLimiter(reqLimit)
+		req.SetFlowLimiter(flowLimit)
+		req.SetStringBody("a=1")
+		return req.Send()
+	}
+
+	if err := send(); err == nil {
+		t.Fatal("expect error for 429")
+	}
+	if reqLimit.GetRateLimit() != 500 || flowLimit.GetRateLimit() != 512*1024 {
+		t.Fatalf("expect rates to be halved, got %d %d", reqLimit.GetRateLimit(), flowLimit.GetRateLimit())
+	}
+	if stats[0].RequestRateLimit != 500 || stats[0].FlowRateLimit != 512*1024 {
+		t.Errorf("expect current rates in stats, got %+v", stats[0])
+	}
+
+	status = http.StatusOK
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if reqLimit.GetRateLimit() != 510 || flowLimit.GetRateLimit() != 512*1024+1024*1024/100 {
+		t.Fatalf("expect rates to be increased, got %d %d", reqLimit.GetRateLimit(), flowLimit.GetRateLimit())
+	}
+}
+
+type testErrBuilder struct{}
+
+func (testErrBuilder) Build(msg, text, reqId string, code int) error {
+	err := reqerr.New(msg, text, reqId, code)
+	if strings.HasPrefix(msg, "E9000") {
+		err.ErrorType = reqerr.InternalServerError
+	}
+	return err
+}
+
+func newFlakyServer(t *testing.T, failures int, bodies *[]string) *httptest.Server {
+	var count int
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, string(body))
+		count++
+		if count <= failures {
+			w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"E9000: internal error"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestSendRetry(t *testing.T) {
+	var bodies []string
+	ts := newFlakyServer(t, 2, &bodies)
+	defer ts.Close()
+
+	policy := config.NewRetryPolicy()
+	policy.BaseBackoff = time.Millisecond
+	cfg := &config.Config{Endpoint: ts.URL, Logger: testLogger, RetryPolicy: policy}
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpUpdateRepo, Method: "PUT", Path: "/v2/repos/repo"}, "", testErrBuilder{}, nil)
+	req.SetStringBody(`{"schema":[]}`)
+
+	if err := req.Send(); err != nil {
+		t.Fatalf("send should succeed after retry, got %v", err)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("expect 3 attempts, got %d", len(bodies))
+	}
+	for _, b := range bodies {
+		if b != `{"schema":[]}` {
+			t.Errorf("body should be rewound before retry, got %q", b)
+		}
+	}
+}
+
+func TestSendNoRetryForNonIdempotent(t *testing.T) {
+	var bodies []string
+	ts := newFlakyServer(t, 1, &bodies)
+	defer ts.Close()
+
+	policy := config.NewRetryPolicy()
+	policy.BaseBackoff = time.Millisecond
+	cfg := &config.Config{Endpoint: ts.URL, Logger: testLogger, RetryPolicy: policy}
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpPostData, Method: "POST", Path: "/v2/repos/repo/data"}, "", testErrBuilder{}, nil)
+	req.SetStringBody("a=1")
+
+	err := req.Send()
+	if v, ok := err.(*reqerr.RequestError); !ok || v.ErrorType != reqerr.InternalServerError {
+		t.Fatalf("expect internal server error, got %v", err)
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("PostData should not be retried without opt-in, got %d attempts", len(bodies))
+	}
+
+	var retried []string
+	ts2 := newFlakyServer(t, 1, &retried)
+	defer ts2.Close()
+	cfg.Endpoint = ts2.URL
+	policy.RetryNonIdempotent = true
+	req = New(cfg, http.DefaultClient, &Operation{Name: base.OpPostData, Method: "POST", Path: "/v2/repos/repo/data"}, "", testErrBuilder{}, nil)
+	req.SetStringBody("a=1")
+	if err := req.Send(); err != nil {
+		t.Fatalf("send should succeed after retry, got %v", err)
+	}
+	if len(retried) != 2 || retried[1] != "a=1" {
+		t.Fatalf("PostData should be retried once with the same body, got %v", retried)
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	var gotPath, gotHeader, gotBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		gotPath, gotHeader, gotBody = r.URL.Path, r.Header.Get("X-Audit"), string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	var order []string
+	var status int
+	var latency time.Duration
+	cfg := (&config.Config{Endpoint: ts.URL, Logger: testLogger}).WithInterceptors(
+		func(call *base.Call, next base.Handler) error {
+			order = append(order, "outer")
+			call.HTTPRequest.Header.Set("X-Audit", call.Operation.Name)
+			err := next(call)
+			status, latency = call.HTTPResponse.StatusCode, call.Latency
+			return err
+		},
+		func(call *base.Call, next base.Handler) error {
+			order = append(order, "inner")
+			call.Operation.Path = "/v2/repos/other"
+			call.Body = strings.NewReader("replaced")
+			return next(call)
+		},
+	)
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpUpdateRepo, Method: "PUT", Path: "/v2/repos/repo"}, "", testErrBuilder{}, nil)
+	req.SetStringBody("origin")
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("unexpected interceptor order %v", order)
+	}
+	if gotPath != "/v2/repos/other" || gotHeader != base.OpUpdateRepo || gotBody != "replaced" {
+		t.Errorf("unexpected request path %q header %q body %q", gotPath, gotHeader, gotBody)
+	}
+	if status != http.StatusOK || latency <= 0 {
+		t.Errorf("expect observed status 200 and latency, got %d %v", status, latency)
+	}
+}
+
+func TestInterceptorOperationPerAttempt(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if len(paths) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	policy := config.NewRetryPolicy()
+	policy.BaseBackoff = time.Millisecond
+	cfg := (&config.Config{Endpoint: ts.URL, Logger: testLogger, RetryPolicy: policy}).WithInterceptors(
+		func(call *base.Call, next base.Handler) error {
+			call.Operation.Path += "/v1"
+			return next(call)
+		},
+	)
+	op := &Operation{Name: base.OpGetRepo, Method: "GET", Path: "/v2/repos/repo"}
+	req := New(cfg, http.DefaultClient, op, "", testErrBuilder{}, nil)
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(paths, ",") != "/v2/repos/repo/v1,/v2/repos/repo/v1" {
+		t.Errorf("expect every attempt to start from the original operation, got %v", paths)
+	}
+	if op.Path != "/v2/repos/repo" {
+		t.Errorf("operation should not be modified by interceptors, got %s", op.Path)
+	}
+}
+
+func TestInterceptorGzipBody(t *testing.T) {
+	var gotEncoding, gotBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Content-Encoding")
+		var body io.Reader = r.Body
+		if gotEncoding == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body = gz
+		}
+		b, _ := ioutil.ReadAll(body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	cfg := (&config.Config{Endpoint: ts.URL, Logger: testLogger, Gzip: true}).WithInterceptors(
+		func(call *base.Call, next base.Handler) error {
+			call.Body = strings.NewReader("replaced")
+			return next(call)
+		},
+	)
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpPostData, Method: "POST", Path: "/v2/repos/repo/data"}, "", testErrBuilder{}, nil)
+	req.SetStringBody("origin")
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if gotEncoding != "gzip" || gotBody != "replaced" {
+		t.Errorf("expect replaced body to be compressed, got encoding %q body %q", gotEncoding, gotBody)
+	}
+}
+
+func TestInterceptorFaultInjection(t *testing.T) {
+	var bodies []string
+	ts := newFlakyServer(t, 0, &bodies)
+	defer ts.Close()
+
+	injected := errors.New("injected fault")
+	policy := config.NewRetryPolicy()
+	policy.BaseBackoff = time.Millisecond
+	cfg := (&config.Config{Endpoint: ts.URL, Logger: testLogger, RetryPolicy: policy}).WithInterceptors(
+		func(call *base.Call, next base.Handler) error {
+			if call.Attempt == 1 {
+				return injected
+			}
+			return next(call)
+		},
+	)
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpGetRepo, Method: "GET", Path: "/v2/repos/repo"}, "", testErrBuilder{}, nil)
+	if err := req.Send(); err != nil {
+		t.Fatalf("send should succeed after injected fault, got %v", err)
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("expect 1 request reaching server, got %d", len(bodies))
+	}
+
+	cfg.RetryPolicy = nil
+	req = New(cfg, http.DefaultClient, &Operation{Name: base.OpGetRepo, Method: "GET", Path: "/v2/repos/repo"}, "", testErrBuilder{}, nil)
+	if err := req.Send(); err != injected {
+		t.Fatalf("expect injected fault, got %v", err)
+	}
+}
+
+type statsRecorder []*base.RequestStats
+
+func (s *statsRecorder) ObserveRequest(stats *base.RequestStats) {
+	*s = append(*s, stats)
+}
+
+func TestSendMetrics(t *testing.T) {
+	var bodies []string
+	ts := newFlakyServer(t, 1, &bodies)
+	defer ts.Close()
+
+	var stats statsRecorder
+	policy := config.NewRetryPolicy()
+	policy.BaseBackoff = time.Millisecond
+	cfg := (&config.Config{Endpoint: ts.URL, Logger: testLogger, RetryPolicy: policy}).WithMetrics(&stats)
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpUpdateRepo, Method: "PUT", Path: "/v2/repos/repo"}, "", testErrBuilder{}, nil)
+	req.SetStringBody(`{"schema":[]}`)
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expect 2 attempts observed, got %d", len(stats))
+	}
+	for i, s := range stats {
+		if s.Operation != base.OpUpdateRepo || s.Attempt != i+1 || s.BytesSent != 13 || s.Latency <= 0 {
+			t.Errorf("unexpected stats of attempt %d: %+v", i+1, s)
+		}
+	}
+	if stats[0].StatusCode != 500 || stats[0].Error == nil {
+		t.Errorf("expect first attempt failed with 500, got %+v", stats[0])
+	}
+	if stats[1].StatusCode != 200 || stats[1].Error != nil {
+		t.Errorf("expect second attempt succeeded, got %+v", stats[1])
+	}
+}
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttributes(attrs ...base.Field) {
+	for _, f := range attrs {
+		s.attrs[f.Key] = f.Value
+	}
+}
+
+func (s *testSpan) SetError(err error) { s.err = err }
+
+func (s *testSpan) End() { s.ended = true }
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, base.Span) {
+	span := &testSpan{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, t, span), span
+}
+
+func (t *testTracer) Inject(ctx context.Context, header http.Header) {
+	if ctx.Value(t) != nil {
+		header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	}
+}
+
+func TestSendTracing(t *testing.T) {
+	var traceparent []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = append(traceparent, r.Header.Get("traceparent"))
+		w.Header().Set(base.HTTPHeaderRequestId, "reqid-1")
+		w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"E18102: repo not found"}`))
+	}))
+	defer ts.Close()
+
+	tracer := &testTracer{}
+	cfg := (&config.Config{Endpoint: ts.URL, Logger: testLogger}).WithTracer(tracer)
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpGetRepo, Method: "GET", Path: "/v2/repos/repo_1"}, "", testErrBuilder{}, nil)
+	if err := req.Send(); err == nil {
+		t.Fatal("expect error")
+	}
+	if len(tracer.spans) != 1 {
+		t.Fatalf("expect 1 span, got %d", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != base.OpGetRepo || !span.ended || span.err == nil {
+		t.Errorf("unexpected span %+v", span)
+	}
+	expect := map[string]interface{}{
+		base.AttrRepo:       "repo_1",
+		base.AttrHTTPStatus: 404,
+		base.AttrRequestId:  "reqid-1",
+		base.AttrAttempts:   1,
+	}
+	for k, v := range expect {
+		if span.attrs[k] != v {
+			t.Errorf("expect attribute %s=%v, got %v", k, v, span.attrs[k])
+		}
+	}
+	if len(traceparent) != 1 || traceparent[0] == "" {
+		t.Errorf("expect traceparent header, got %v", traceparent)
+	}
+}
+
+func TestSendClockSkew(t *testing.T) {
+	var dates []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverTime := time.Now().Add(-time.Hour)
+		w.Header().Set("Date", serverTime.UTC().Format(http.TimeFormat))
+		dates = append(dates, r.Header.Get("Date"))
+		date, _ := http.ParseTime(r.Header.Get("Date"))
+		if d := serverTime.Sub(date); d > time.Minute || d < -time.Minute {
+			w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	cfg := &config.Config{Endpoint: ts.URL, Logger: testLogger, Clock: base.NewClock()}
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpUpdateRepo, Method: "PUT", Path: "/v2/repos/repo"}, "", testErrBuilder{}, nil)
+	req.SetStringBody(`{"schema":[]}`)
+	if err := req.Send(); err != nil {
+		t.Fatalf("expect request re-signed after clock skew detected, got %v", err)
+	}
+	if len(dates) != 2 || dates[0] == dates[1] {
+		t.Fatalf("expect 2 requests with different Date header, got %v", dates)
+	}
+	if offset := cfg.Clock.Offset(); offset > -59*time.Minute || offset < -61*time.Minute {
+		t.Fatalf("unexpected clock offset %v", offset)
+	}
+
+	cfg.Clock = nil
+	req = New(cfg, http.DefaultClient, &Operation{Name: base.OpGetRepo, Method: "GET", Path: "/v2/repos/repo"}, "", testErrBuilder{}, nil)
+	if err := req.Send(); err == nil {
+		t.Fatal("expect unauthorized without clock correction")
+	}
+}
@@ -0,0 +1,57 @@
+//go:build go1.21
+// +build go1.21
+
+package request
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+	"github.com/qiniu/pandora-go-sdk/base/config"
+)
+
+func TestSendLogRecord(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(base.HTTPHeaderRequestId, "reqid-1")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	var buf strings.Builder
+	logger := base.NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	cfg := &config.Config{Endpoint: ts.URL, Logger: logger}
+	req := New(cfg, http.DefaultClient, &Operation{Name: base.OpPostData, Method: "POST", Path: "/v2/repos/repo/data"}, "", testErrBuilder{}, nil)
+	req.SetStringBody("f1=a")
+	if err := req.Send(); err != nil {
+		t.Fatal(err)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &record); err != nil {
+		t.Fatalf("expect one json record, got %q: %v", buf.String(), err)
+	}
+	exp := map[string]interface{}{
+		"level":     "DEBUG",
+		"msg":       "send request",
+		"op":        base.OpPostData,
+		"method":    "POST",
+		"path":      "/v2/repos/repo/data",
+		"status":    float64(200),
+		"reqid":     "reqid-1",
+		"body_size": float64(4),
+		"attempt":   float64(1),
+	}
+	for k, v := range exp {
+		if record[k] != v {
+			t.Errorf("expect %s=%v, got %v", k, v, record[k])
+		}
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Error("expect duration in record")
+	}
+}
@@ -0,0 +1,106 @@
+//go:build go1.21
+// +build go1.21
+
+package base
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// SlogLogger 把SDK的日志输出到标准库的slog.Logger，结构化日志中的字段会转换为slog的属性
+type SlogLogger struct {
+	Logger *slog.Logger
+	level  LogLevelType
+}
+
+// NewSlogLogger 返回一个使用l输出日志的Logger，l为nil时使用slog.Default()
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogLogger{Logger: l}
+}
+
+func slogLevel(level LogLevelType) slog.Level {
+	switch level {
+	case LogDebug:
+		return slog.LevelDebug
+	case LogInfo:
+		return slog.LevelInfo
+	case LogWarn:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+func (l *SlogLogger) SetLoggerLevel(level LogLevelType) {
+	l.level = level
+}
+
+func (l *SlogLogger) Log(level LogLevelType, msg string, fields ...Field) {
+	if !l.level.AtMost(level) {
+		return
+	}
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	l.Logger.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+	switch level {
+	case LogPanic:
+		panic(msg)
+	case LogFatal:
+		os.Exit(1)
+	}
+}
+
+func (l *SlogLogger) Debug(v ...interface{}) {
+	l.Log(LogDebug, fmt.Sprint(v...))
+}
+
+func (l *SlogLogger) Debugf(format string, v ...interface{}) {
+	l.Log(LogDebug, fmt.Sprintf(format, v...))
+}
+
+func (l *SlogLogger) Info(v ...interface{}) {
+	l.Log(LogInfo, fmt.Sprint(v...))
+}
+
+func (l *SlogLogger) Infof(format string, v ...interface{}) {
+	l.Log(LogInfo, fmt.Sprintf(format, v...))
+}
+
+func (l *SlogLogger) Warn(v ...interface{}) {
+	l.Log(LogWarn, fmt.Sprint(v...))
+}
+
+func (l *SlogLogger) Warnf(format string, v ...interface{}) {
+	l.Log(LogWarn, fmt.Sprintf(format, v...))
+}
+
+func (l *SlogLogger) Error(v ...interface{}) {
+	l.Log(LogError, fmt.Sprint(v...))
+}
+
+func (l *SlogLogger) Errorf(format string, v ...interface{}) {
+	l.Log(LogError, fmt.Sprintf(format, v...))
+}
+
+func (l *SlogLogger) Panic(v ...interface{}) {
+	l.Log(LogPanic, fmt.Sprint(v...))
+}
+
+func (l *SlogLogger) Panicf(format string, v ...interface{}) {
+	l.Log(LogPanic, fmt.Sprintf(format, v...))
+}
+
+func (l *SlogLogger) Fatal(v ...interface{}) {
+	l.Log(LogFatal, fmt.Sprint(v...))
+}
+
+func (l *SlogLogger) Fatalf(format string, v ...interface{}) {
+	l.Log(LogFatal, fmt.Sprintf(format, v...))
+}
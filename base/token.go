@@ -5,10 +5,12 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -37,6 +39,11 @@ func (t *TokenDesc) SetHeader(k, v string) {
 }
 
 func (t *TokenDesc) Validate() (err error) {
+	return t.ValidateAt(time.Now())
+}
+
+// ValidateAt 使用now检查token是否已经过期，now可以是按服务端时间校正之后的时间
+func (t *TokenDesc) ValidateAt(now time.Time) (err error) {
 	if t.Url == "" {
 		err = fmt.Errorf("url in token description should not be empty")
 		return
@@ -45,8 +52,7 @@ func (t *TokenDesc) Validate() (err error) {
 		err = fmt.Errorf("method should be one of \"GET\", \"PUT\", \"POST\" and \"DELETE\"")
 		return
 	}
-	curr := time.Now().Unix()
-	if t.Expires < curr {
+	if t.Expires < now.Unix() {
 		err = fmt.Errorf("token has been expired before making token")
 		return
 	}
@@ -74,11 +80,19 @@ func newTokenDesc(t *TokenDesc) *tokenDesc {
 }
 
 func MakeTokenInternal(ak, sk string, desc *TokenDesc) (token string, err error) {
-	if err = desc.Validate(); err != nil {
+	return MakeTokenWithClock(ak, sk, desc, nil)
+}
+
+// MakeTokenWithClock 与MakeTokenInternal相同，但是使用clock校正之后的时间检查Expires
+func MakeTokenWithClock(ak, sk string, desc *TokenDesc, clock *Clock) (token string, err error) {
+	if err = desc.ValidateAt(clock.Now()); err != nil {
 		return
 	}
 
-	td := newTokenDesc(desc)
+	return signTokenDesc(ak, sk, newTokenDesc(desc))
+}
+
+func signTokenDesc(ak, sk string, td *tokenDesc) (token string, err error) {
 	marshaledTokenDesc, err := json.Marshal(td)
 	if err != nil {
 		return
@@ -91,3 +105,98 @@ func MakeTokenInternal(ak, sk string, desc *TokenDesc) (token string, err error)
 
 	return fmt.Sprintf("Pandora %s:%s:%s", ak, encodedSign, encodedTokenDesc), nil
 }
+
+// ParseToken和VerifyToken返回的错误，可以通过errors.Is判断
+var (
+	ErrMalformedToken    = errors.New("malformed token")
+	ErrTokenSignMismatch = errors.New("token signature mismatch")
+	ErrTokenExpired      = errors.New("token expired")
+	ErrTokenMismatch     = errors.New("token does not match request")
+)
+
+// Token 是ParseToken从"Pandora ak:sign:desc"中解析出来的内容
+type Token struct {
+	AccessKey   string
+	Sign        string
+	EncodedDesc string
+
+	Resource    string
+	Expires     int64
+	Method      string
+	ContentType string
+	ContentMD5  string
+	Headers     string
+}
+
+// ParseToken 解析token，不校验签名和有效期，token可以不带"Pandora "前缀
+func ParseToken(token string) (*Token, error) {
+	parts := strings.Split(strings.TrimPrefix(token, "Pandora "), ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expect ak:sign:desc", ErrMalformedToken)
+	}
+	buf, err := base64.URLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode desc failed, %v", ErrMalformedToken, err)
+	}
+	var desc tokenDesc
+	if err = json.Unmarshal(buf, &desc); err != nil {
+		return nil, fmt.Errorf("%w: unmarshal desc failed, %v", ErrMalformedToken, err)
+	}
+	return &Token{
+		AccessKey:   parts[0],
+		Sign:        parts[1],
+		EncodedDesc: parts[2],
+		Resource:    desc.Resource,
+		Expires:     desc.Expires,
+		Method:      desc.Method,
+		ContentType: desc.ContentType,
+		ContentMD5:  desc.ContentMD5,
+		Headers:     desc.Headers,
+	}, nil
+}
+
+// ExpiresAt 返回token的过期时间
+func (t *Token) ExpiresAt() time.Time {
+	return time.Unix(t.Expires, 0)
+}
+
+// VerifyToken 使用sk校验token的签名和有效期，req不为nil时还会校验token是否允许用于这个请求
+func VerifyToken(sk, token string, req *http.Request) error {
+	return VerifyTokenAt(sk, token, req, time.Now())
+}
+
+// VerifyTokenAt 与VerifyToken相同，但是以now作为当前时间判断token是否过期
+func VerifyTokenAt(sk, token string, req *http.Request, now time.Time) error {
+	t, err := ParseToken(token)
+	if err != nil {
+		return err
+	}
+	h := hmac.New(sha1.New, []byte(sk))
+	io.WriteString(h, t.EncodedDesc)
+	sign, err := base64.URLEncoding.DecodeString(t.Sign)
+	if err != nil || !hmac.Equal(sign, h.Sum(nil)) {
+		return ErrTokenSignMismatch
+	}
+	if t.Expires < now.Unix() {
+		return fmt.Errorf("%w at %s", ErrTokenExpired, t.ExpiresAt().Format(time.RFC3339))
+	}
+	if req == nil {
+		return nil
+	}
+	if t.Method != req.Method {
+		return fmt.Errorf("%w: method %s, request method %s", ErrTokenMismatch, t.Method, req.Method)
+	}
+	if resource := SignQiniuResource(req.URL.Path, req.URL.Query()); t.Resource != resource {
+		return fmt.Errorf("%w: resource %s, request resource %s", ErrTokenMismatch, t.Resource, resource)
+	}
+	if t.ContentType != "" && t.ContentType != req.Header.Get(HTTPHeaderContentType) {
+		return fmt.Errorf("%w: content type %s, request content type %s", ErrTokenMismatch, t.ContentType, req.Header.Get(HTTPHeaderContentType))
+	}
+	if t.ContentMD5 != "" && t.ContentMD5 != req.Header.Get(HTTPHeaderContentMD5) {
+		return fmt.Errorf("%w: content md5", ErrTokenMismatch)
+	}
+	if t.Headers != SignQiniuHeader(req.Header) {
+		return fmt.Errorf("%w: headers", ErrTokenMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package base
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenScope 是签发token的模板，Path中的{repo}会被替换为repo名
+type TokenScope struct {
+	Name        string
+	Method      string
+	Path        string
+	ContentType string //不为空时token只能用于该Content-Type的请求
+}
+
+// 常用的token模板
+var (
+	ScopePipelinePostData = TokenScope{Name: "pipeline.postData", Method: MethodPost, Path: "/v2/repos/{repo}/data"}
+	ScopeLogdbSendLog     = TokenScope{Name: "logdb.sendLog", Method: MethodPost, Path: "/v5/repos/{repo}/data"}
+	ScopeLogdbQueryLog    = TokenScope{Name: "logdb.queryLog", Method: MethodGet, Path: "/v5/repos/{repo}/search"}
+	ScopeTsdbWritePoints  = TokenScope{Name: "tsdb.writePoints", Method: MethodPost, Path: "/v4/repos/{repo}/points"}
+	ScopeTsdbQueryPoints  = TokenScope{Name: "tsdb.queryPoints", Method: MethodPost, Path: "/v4/repos/{repo}/query"}
+)
+
+const (
+	defaultTokenTTL           = time.Hour
+	defaultTokenRefreshBefore = time.Minute
+)
+
+var tokenRepoNamePattern = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]{0,127}$")
+
+var errTokenIssuerNoAuthorize = errors.New("token issuer has no Authorize configured")
+
+// IssuedToken 是TokenIssuer签发的token，Expires为unix时间戳，单位为秒
+type IssuedToken struct {
+	Token   string `json:"token"`
+	Expires int64  `json:"expires"`
+}
+
+// TokenIssuer 按模板签发token，并在token过期前RefreshBefore之内一直返回缓存的token。
+// TokenIssuer同时实现了http.Handler，GET ?scope=pipeline.postData&repo=repo_name返回IssuedToken
+type TokenIssuer struct {
+	Credentials   CredentialsProvider
+	TTL           time.Duration //token的有效期，默认为1小时
+	RefreshBefore time.Duration //token过期前多久重新签发，默认为1分钟
+	Clock         *Clock        //不为nil时使用校正之后的时间计算过期时间
+	// Authorize 在通过http.Handler签发token之前校验请求，返回错误时响应403，为nil时拒绝所有请求
+	Authorize func(r *http.Request, scope, repo string) error
+
+	mu     sync.Mutex
+	scopes map[string]TokenScope
+	cache  map[string]*IssuedToken
+}
+
+// NewTokenIssuer 返回一个注册了常用模板的TokenIssuer
+func NewTokenIssuer(credentials CredentialsProvider) *TokenIssuer {
+	t := &TokenIssuer{
+		Credentials:   credentials,
+		TTL:           defaultTokenTTL,
+		RefreshBefore: defaultTokenRefreshBefore,
+		scopes:        map[string]TokenScope{},
+		cache:         map[string]*IssuedToken{},
+	}
+	t.Register(ScopePipelinePostData, ScopeLogdbSendLog, ScopeLogdbQueryLog, ScopeTsdbWritePoints, ScopeTsdbQueryPoints)
+	return t
+}
+
+// Register 注册token模板，同名的模板会被替换，已经缓存的token会被丢弃
+func (t *TokenIssuer) Register(scopes ...TokenScope) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range scopes {
+		t.scopes[s.Name] = s
+		for key := range t.cache {
+			if strings.HasPrefix(key, s.Name+"/") {
+				delete(t.cache, key)
+			}
+		}
+	}
+}
+
+// Scopes 返回已注册的模板名
+func (t *TokenIssuer) Scopes() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	names := make([]string, 0, len(t.scopes))
+	for name := range t.scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Issue 返回scope模板下repo对应的token，缓存按AccessKey区分，轮换密钥之后会重新签发
+func (t *TokenIssuer) Issue(scope, repo string) (*IssuedToken, error) {
+	if !tokenRepoNamePattern.MatchString(repo) {
+		return nil, fmt.Errorf("invalid repo name %q", repo)
+	}
+	t.mu.Lock()
+	s, ok := t.scopes[scope]
+	t.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown token scope %s", scope)
+	}
+
+	// Retrieve可能访问外部服务，不能在持有锁的时候调用
+	creds, err := t.Credentials.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+	key := scope + "/" + repo + "/" + creds.AccessKey
+	now := t.Clock.Now()
+	t.mu.Lock()
+	cached, ok := t.cache[key]
+	t.mu.Unlock()
+	if ok && now.Add(t.RefreshBefore).Unix() < cached.Expires {
+		return cached, nil
+	}
+
+	desc := &TokenDesc{
+		Url:         strings.Replace(s.Path, "{repo}", repo, -1),
+		Method:      s.Method,
+		ContentType: s.ContentType,
+		Expires:     now.Add(t.TTL).Unix(),
+	}
+	token, err := MakeTokenWithClock(creds.AccessKey, creds.SecretKey, desc, t.Clock)
+	if err != nil {
+		return nil, err
+	}
+	issued := &IssuedToken{Token: token, Expires: desc.Expires}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cur, ok := t.scopes[scope]; !ok || cur != s {
+		// 签发期间模板被替换，不缓存按旧模板签发的token
+		return issued, nil
+	}
+	t.cache[key] = issued
+	return issued, nil
+}
+
+func (t *TokenIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != MethodGet {
+		writeTokenError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	scope, repo := r.URL.Query().Get("scope"), r.URL.Query().Get("repo")
+	t.mu.Lock()
+	_, ok := t.scopes[scope]
+	t.mu.Unlock()
+	if !ok {
+		writeTokenError(w, http.StatusNotFound, fmt.Errorf("unknown token scope %q", scope))
+		return
+	}
+	if !tokenRepoNamePattern.MatchString(repo) {
+		writeTokenError(w, http.StatusBadRequest, fmt.Errorf("invalid repo name %q", repo))
+		return
+	}
+	if t.Authorize == nil {
+		writeTokenError(w, http.StatusForbidden, errTokenIssuerNoAuthorize)
+		return
+	}
+	if err := t.Authorize(r, scope, repo); err != nil {
+		writeTokenError(w, http.StatusForbidden, err)
+		return
+	}
+	issued, err := t.Issue(scope, repo)
+	if err != nil {
+		writeTokenError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set(HTTPHeaderContentType, ContentTypeJson)
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(issued)
+}
+
+func writeTokenError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set(HTTPHeaderContentType, ContentTypeJson)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
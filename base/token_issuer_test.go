@@ -0,0 +1,140 @@
+package base
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// advanceClock 把clock校正之后的时间向后移动d
+func advanceClock(c *Clock, d time.Duration) {
+	atomic.AddInt64(&c.offset, int64(d))
+}
+
+func TestTokenIssuerCache(t *testing.T) {
+	issuer := NewTokenIssuer(NewStaticCredentialsProvider("ak", "sk"))
+	issuer.Clock = NewClock()
+
+	start := time.Now()
+	first, err := issuer.Issue(ScopePipelinePostData.Name, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Expires < start.Add(time.Hour).Unix() || first.Expires > time.Now().Add(time.Hour).Unix() ||
+		!strings.HasPrefix(first.Token, "Pandora ak:") {
+		t.Fatalf("unexpected token %+v", first)
+	}
+	advanceClock(issuer.Clock, 30*time.Minute)
+	if cached, _ := issuer.Issue(ScopePipelinePostData.Name, "repo"); cached != first {
+		t.Fatal("expect cached token")
+	}
+	if other, _ := issuer.Issue(ScopeTsdbWritePoints.Name, "repo"); other == first {
+		t.Fatal("expect different token for another scope")
+	}
+	advanceClock(issuer.Clock, 29*time.Minute+30*time.Second)
+	renewed, err := issuer.Issue(ScopePipelinePostData.Name, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed == first || renewed.Expires <= first.Expires {
+		t.Fatal("expect token renewed shortly before expires")
+	}
+
+	if _, err = issuer.Issue("unknown", "repo"); err == nil {
+		t.Error("expect error for unknown scope")
+	}
+	if _, err = issuer.Issue(ScopePipelinePostData.Name, "../repo"); err == nil {
+		t.Error("expect error for invalid repo name")
+	}
+}
+
+func TestTokenIssuerCredentialsRotation(t *testing.T) {
+	var issuer *TokenIssuer
+	ak := "ak1"
+	issuer = NewTokenIssuer(CredentialsFunc(func() (Credentials, error) {
+		// Retrieve在锁外调用，可以再访问issuer
+		issuer.Scopes()
+		return Credentials{AccessKey: ak, SecretKey: "sk"}, nil
+	}))
+
+	first, err := issuer.Issue(ScopePipelinePostData.Name, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached, _ := issuer.Issue(ScopePipelinePostData.Name, "repo"); cached != first {
+		t.Fatal("expect cached token")
+	}
+	ak = "ak2"
+	rotated, err := issuer.Issue(ScopePipelinePostData.Name, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated == first || !strings.HasPrefix(rotated.Token, "Pandora ak2:") {
+		t.Fatalf("expect token signed with rotated credentials, got %+v", rotated)
+	}
+}
+
+func TestTokenIssuerClockSkew(t *testing.T) {
+	issuer := NewTokenIssuer(NewStaticCredentialsProvider("ak", "sk"))
+	issuer.Clock = NewClock()
+	// 服务端时间比本地时间慢的部分超过了TTL
+	advanceClock(issuer.Clock, -2*time.Hour)
+
+	issued, err := issuer.Issue(ScopePipelinePostData.Name, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expires := time.Now().Add(-time.Hour).Unix(); issued.Expires < expires-1 || issued.Expires > expires+1 {
+		t.Errorf("expect token to expire an hour after server time, got %d", issued.Expires)
+	}
+}
+
+func TestTokenIssuerHandler(t *testing.T) {
+	issuer := NewTokenIssuer(NewStaticCredentialsProvider("ak", "sk"))
+	issuer.Authorize = func(r *http.Request, scope, repo string) error {
+		if repo == "secret" {
+			return errors.New("forbidden")
+		}
+		return nil
+	}
+	tests := []struct {
+		method string
+		query  string
+		status int
+	}{
+		{"GET", "scope=logdb.queryLog&repo=repo", http.StatusOK},
+		{"GET", "scope=logdb.queryLog&repo=secret", http.StatusForbidden},
+		{"GET", "scope=logdb.queryLog&repo=a/b", http.StatusBadRequest},
+		{"GET", "scope=unknown&repo=repo", http.StatusNotFound},
+		{"POST", "scope=logdb.queryLog&repo=repo", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		issuer.ServeHTTP(w, httptest.NewRequest(tt.method, "/token?"+tt.query, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expect status %d, got %d", tt.method, tt.query, tt.status, w.Code)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var issued IssuedToken
+		if err := json.Unmarshal(w.Body.Bytes(), &issued); err != nil || issued.Token == "" || issued.Expires == 0 {
+			t.Errorf("unexpected response %s, err %v", w.Body.String(), err)
+		}
+	}
+}
+
+func TestTokenIssuerHandlerWithoutAuthorize(t *testing.T) {
+	issuer := NewTokenIssuer(NewStaticCredentialsProvider("ak", "sk"))
+	w := httptest.NewRecorder()
+	issuer.ServeHTTP(w, httptest.NewRequest("GET", "/token?scope=logdb.queryLog&repo=repo", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expect status %d without Authorize, got %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package base
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseAndVerifyToken(t *testing.T) {
+	desc := &TokenDesc{
+		Url:         "/v2/repos/repo/data",
+		Method:      MethodPost,
+		ContentType: ContentTypeText,
+		Expires:     time.Now().Add(time.Hour).Unix(),
+	}
+	token, err := MakeTokenInternal("ak", "sk", desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.AccessKey != "ak" || parsed.Resource != desc.Url || parsed.Method != MethodPost ||
+		parsed.ContentType != ContentTypeText || parsed.Expires != desc.Expires {
+		t.Fatalf("unexpected token %+v", parsed)
+	}
+
+	req, _ := http.NewRequest(MethodPost, "https://pipeline.qiniu.com/v2/repos/repo/data", nil)
+	req.Header.Set(HTTPHeaderContentType, ContentTypeText)
+	if err = VerifyToken("sk", token, req); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyToken("wrong", token, req); !errors.Is(err, ErrTokenSignMismatch) {
+		t.Errorf("expect ErrTokenSignMismatch, got %v", err)
+	}
+	other, _ := http.NewRequest(MethodPost, "https://pipeline.qiniu.com/v2/repos/other/data", nil)
+	other.Header.Set(HTTPHeaderContentType, ContentTypeText)
+	if err = VerifyToken("sk", token, other); !errors.Is(err, ErrTokenMismatch) {
+		t.Errorf("expect ErrTokenMismatch, got %v", err)
+	}
+	req.Header.Set(HTTPHeaderContentType, ContentTypeJson)
+	if err = VerifyToken("sk", token, req); !errors.Is(err, ErrTokenMismatch) {
+		t.Errorf("expect ErrTokenMismatch for content type, got %v", err)
+	}
+
+	// MakeTokenInternal不允许签发已经过期的token
+	expired, _ := signTokenDesc("ak", "sk", &tokenDesc{Resource: desc.Url, Method: MethodPost, Expires: time.Now().Add(-time.Minute).Unix()})
+	if err = VerifyToken("sk", expired, nil); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expect ErrTokenExpired, got %v", err)
+	}
+	if err = VerifyTokenAt("sk", expired, nil, time.Now().Add(-2*time.Minute)); err != nil {
+		t.Errorf("expect token valid at an earlier time, got %v", err)
+	}
+	if err = VerifyTokenAt("sk", token, req, time.Now().Add(2*time.Hour)); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expect ErrTokenExpired at a later time, got %v", err)
+	}
+
+	for _, s := range []string{"Pandora ak:sign", "Pandora ak:sign:!!!", "ak:sign:bm90IGpzb24="} {
+		if _, err = ParseToken(s); !errors.Is(err, ErrMalformedToken) {
+			t.Errorf("%s: expect ErrMalformedToken, got %v", s, err)
+		}
+	}
+}
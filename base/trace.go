@@ -0,0 +1,48 @@
+package base
+
+import (
+	"context"
+	"net/http"
+)
+
+// Span 对应一次API调用，属性的名字参考OpenTelemetry的语义约定
+type Span interface {
+	SetAttributes(attrs ...Field)
+	SetError(err error)
+	End()
+}
+
+// Tracer 为每次Send创建一个以Operation.Name命名的span，
+// 并通过Inject把span的上下文写入请求的header，例如W3C的traceparent
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+	Inject(ctx context.Context, header http.Header)
+}
+
+// NoopTracer 是默认的Tracer，不记录任何信息
+type NoopTracer struct{}
+
+func (NoopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (NoopTracer) Inject(ctx context.Context, header http.Header) {}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attrs ...Field) {}
+
+func (noopSpan) SetError(err error) {}
+
+func (noopSpan) End() {}
+
+// span的属性名
+const (
+	AttrOperation  = "pandora.operation"
+	AttrRepo       = "pandora.repo"
+	AttrRequestId  = "pandora.reqid"
+	AttrAttempts   = "pandora.attempts"
+	AttrHTTPMethod = "http.request.method"
+	AttrHTTPPath   = "url.path"
+	AttrHTTPStatus = "http.response.status_code"
+)
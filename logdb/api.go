@@ -1,15 +1,20 @@
 package logdb
 
 import (
+	"context"
 	"net/url"
 
 	. "github.com/qiniu/pandora-go-sdk/base"
 )
 
-func (c *Logdb) CreateRepo(input *CreateRepoInput) (err error) {
+func (c *Logdb) CreateRepo(input *CreateRepoInput) error {
+	return c.CreateRepoWithContext(context.Background(), input)
+}
+
+func (c *Logdb) CreateRepoWithContext(ctx context.Context, input *CreateRepoInput) (err error) {
 	op := c.newOperation(OpCreateRepo, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -17,12 +22,16 @@ func (c *Logdb) CreateRepo(input *CreateRepoInput) (err error) {
 	return req.Send()
 }
 
-func (c *Logdb) CreateRepoFromDSL(input *CreateRepoDSLInput) (err error) {
+func (c *Logdb) CreateRepoFromDSL(input *CreateRepoDSLInput) error {
+	return c.CreateRepoFromDSLWithContext(context.Background(), input)
+}
+
+func (c *Logdb) CreateRepoFromDSLWithContext(ctx context.Context, input *CreateRepoDSLInput) (err error) {
 	schemas, err := toSchema(input.DSL, 0)
 	if err != nil {
 		return
 	}
-	return c.CreateRepo(&CreateRepoInput{
+	return c.CreateRepoWithContext(ctx, &CreateRepoInput{
 		LogdbToken: input.LogdbToken,
 		RepoName:   input.RepoName,
 		Region:     input.Region,
@@ -31,10 +40,14 @@ func (c *Logdb) CreateRepoFromDSL(input *CreateRepoDSLInput) (err error) {
 	})
 }
 
-func (c *Logdb) UpdateRepo(input *UpdateRepoInput) (err error) {
+func (c *Logdb) UpdateRepo(input *UpdateRepoInput) error {
+	return c.UpdateRepoWithContext(context.Background(), input)
+}
+
+func (c *Logdb) UpdateRepoWithContext(ctx context.Context, input *UpdateRepoInput) (err error) {
 	op := c.newOperation(OpUpdateRepo, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -42,44 +55,66 @@ func (c *Logdb) UpdateRepo(input *UpdateRepoInput) (err error) {
 	return req.Send()
 }
 
-func (c *Logdb) GetRepo(input *GetRepoInput) (output *GetRepoOutput, err error) {
+func (c *Logdb) GetRepo(input *GetRepoInput) (*GetRepoOutput, error) {
+	return c.GetRepoWithContext(context.Background(), input)
+}
+
+func (c *Logdb) GetRepoWithContext(ctx context.Context, input *GetRepoInput) (output *GetRepoOutput, err error) {
 	op := c.newOperation(OpGetRepo, input.RepoName)
 
 	output = &GetRepoOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Logdb) ListRepos(input *ListReposInput) (output *ListReposOutput, err error) {
+func (c *Logdb) ListRepos(input *ListReposInput) (*ListReposOutput, error) {
+	return c.ListReposWithContext(context.Background(), input)
+}
+
+func (c *Logdb) ListReposWithContext(ctx context.Context, input *ListReposInput) (output *ListReposOutput, err error) {
 	op := c.newOperation(OpListRepos)
 
 	output = &ListReposOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Logdb) DeleteRepo(input *DeleteRepoInput) (err error) {
+func (c *Logdb) DeleteRepo(input *DeleteRepoInput) error {
+	return c.DeleteRepoWithContext(context.Background(), input)
+}
+
+func (c *Logdb) DeleteRepoWithContext(ctx context.Context, input *DeleteRepoInput) (err error) {
 	op := c.newOperation(OpDeleteRepo, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Logdb) SendLog(input *SendLogInput) (output *SendLogOutput, err error) {
+func (c *Logdb) SendLog(input *SendLogInput) (*SendLogOutput, error) {
+	return c.SendLogWithContext(context.Background(), input)
+}
+
+func (c *Logdb) SendLogWithContext(ctx context.Context, input *SendLogInput) (output *SendLogOutput, err error) {
 	op := c.newOperation(OpSendLog, input.RepoName, input.OmitInvalidLog)
 
 	output = &SendLogOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	buf, err := input.Logs.Buf()
 	if err != nil {
 		return
 	}
 	req.SetBufferBody(buf)
 	req.SetHeader(HTTPHeaderContentType, ContentTypeJson)
+	req.SetFlowLimiter(c.flowLimit)
+	req.SetReqLimiter(c.reqLimit)
 	return output, req.Send()
 }
 
-func (c *Logdb) QueryLog(input *QueryLogInput) (output *QueryLogOutput, err error) {
+func (c *Logdb) QueryLog(input *QueryLogInput) (*QueryLogOutput, error) {
+	return c.QueryLogWithContext(context.Background(), input)
+}
+
+func (c *Logdb) QueryLogWithContext(ctx context.Context, input *QueryLogInput) (output *QueryLogOutput, err error) {
 	var highlight bool
 	if input.Highlight != nil {
 		highlight = true
@@ -87,24 +122,61 @@ func (c *Logdb) QueryLog(input *QueryLogInput) (output *QueryLogOutput, err erro
 	op := c.newOperation(OpQueryLog, input.RepoName, url.QueryEscape(input.Query), input.Sort, input.From, input.Size, highlight)
 
 	output = &QueryLogOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	if input.Highlight != nil {
 		if err = req.SetVariantBody(input.Highlight); err != nil {
 			return
 		}
 		req.SetHeader(HTTPHeaderContentType, ContentTypeJson)
 	}
+	req.SetFlowLimiter(c.flowLimit)
+	req.SetReqLimiter(c.reqLimit)
 	return output, req.Send()
 }
 
-func (c *Logdb) QueryHistogramLog(input *QueryHistogramLogInput) (output *QueryHistogramLogOutput, err error) {
+func (c *Logdb) QueryHistogramLog(input *QueryHistogramLogInput) (*QueryHistogramLogOutput, error) {
+	return c.QueryHistogramLogWithContext(context.Background(), input)
+}
+
+func (c *Logdb) QueryHistogramLogWithContext(ctx context.Context, input *QueryHistogramLogInput) (output *QueryHistogramLogOutput, err error) {
 	op := c.newOperation(OpQueryHistogramLog, input.RepoName, url.QueryEscape(input.Query), input.From, input.To, input.Field)
 
 	output = &QueryHistogramLogOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
+	return output, req.Send()
+}
+
+func (c *Logdb) PutRepoConfig(input *PutRepoConfigInput) error {
+	return c.PutRepoConfigWithContext(context.Background(), input)
+}
+
+func (c *Logdb) PutRepoConfigWithContext(ctx context.Context, input *PutRepoConfigInput) (err error) {
+	op := c.newOperation(OpPutRepoConfig, input.RepoName)
+
+	req := c.newRequest(ctx, op, input.Token, nil)
+	if err = req.SetVariantBody(input); err != nil {
+		return
+	}
+	req.SetHeader(HTTPHeaderContentType, ContentTypeJson)
+	return req.Send()
+}
+
+func (c *Logdb) GetRepoConfig(input *GetRepoConfigInput) (*GetRepoConfigOutput, error) {
+	return c.GetRepoConfigWithContext(context.Background(), input)
+}
+
+func (c *Logdb) GetRepoConfigWithContext(ctx context.Context, input *GetRepoConfigInput) (output *GetRepoConfigOutput, err error) {
+	op := c.newOperation(OpGetRepoConfig, input.RepoName)
+
+	output = &GetRepoConfigOutput{}
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
 func (c *Logdb) MakeToken(desc *TokenDesc) (string, error) {
-	return MakeTokenInternal(c.Config.Ak, c.Config.Sk, desc)
+	creds, err := c.Config.RetrieveCredentials()
+	if err != nil {
+		return "", err
+	}
+	return MakeTokenWithClock(creds.AccessKey, creds.SecretKey, desc, c.Config.Clock)
 }
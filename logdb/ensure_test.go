@@ -0,0 +1,33 @@
+package logdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSchema(t *testing.T) {
+	existing := []RepoSchemaEntry{
+		{Key: "f1", ValueType: "string", SearchWay: "keyword"},
+		{Key: "f2", ValueType: "object", Schemas: []RepoSchemaEntry{{Key: "n1", ValueType: "long"}}},
+	}
+	desired := []RepoSchemaEntry{
+		{Key: "f1", ValueType: "string", Analyzer: "standard"},
+		{Key: "f2", ValueType: "object", Schemas: []RepoSchemaEntry{{Key: "n1", ValueType: "long"}, {Key: "n2", ValueType: "date"}}},
+		{Key: "f3", ValueType: "float"},
+	}
+	diff, merged := DiffSchema(existing, desired)
+	if exp := []string{"f2.n2", "f3"}; !reflect.DeepEqual(diff.Added, exp) {
+		t.Errorf("expect added %v, got %v", exp, diff.Added)
+	}
+	if len(diff.Conflicts) != 1 || diff.Conflicts[0].Key != "f1" {
+		t.Errorf("expect conflict on f1, got %v", diff.Conflicts)
+	}
+	if len(merged) != 3 || len(merged[1].Schemas) != 2 || merged[0].SearchWay != "keyword" {
+		t.Errorf("unexpected merged schema %v", merged)
+	}
+
+	diff, _ = DiffSchema(existing, []RepoSchemaEntry{{Key: "f1", ValueType: "string", Analyzer: "keyword"}})
+	if len(diff.Conflicts) != 0 || !reflect.DeepEqual(diff.Extra, []string{"f2"}) {
+		t.Errorf("unexpected diff %+v", diff)
+	}
+}
@@ -1,25 +1,57 @@
 package logdb
 
 import (
+	"context"
+
 	"github.com/qiniu/pandora-go-sdk/base"
 )
 
 type LogdbAPI interface {
 	CreateRepo(*CreateRepoInput) error
 
+	CreateRepoWithContext(context.Context, *CreateRepoInput) error
+
+	EnsureRepo(*CreateRepoInput) (*EnsureRepoOutput, error)
+
+	EnsureRepoWithContext(context.Context, *CreateRepoInput) (*EnsureRepoOutput, error)
+
 	GetRepo(*GetRepoInput) (*GetRepoOutput, error)
 
+	GetRepoWithContext(context.Context, *GetRepoInput) (*GetRepoOutput, error)
+
 	ListRepos(*ListReposInput) (*ListReposOutput, error)
 
+	ListReposWithContext(context.Context, *ListReposInput) (*ListReposOutput, error)
+
 	DeleteRepo(*DeleteRepoInput) error
 
+	DeleteRepoWithContext(context.Context, *DeleteRepoInput) error
+
 	UpdateRepo(*UpdateRepoInput) error
 
+	UpdateRepoWithContext(context.Context, *UpdateRepoInput) error
+
 	SendLog(*SendLogInput) (*SendLogOutput, error)
 
+	SendLogWithContext(context.Context, *SendLogInput) (*SendLogOutput, error)
+
 	QueryLog(*QueryLogInput) (*QueryLogOutput, error)
 
+	QueryLogWithContext(context.Context, *QueryLogInput) (*QueryLogOutput, error)
+
 	QueryHistogramLog(*QueryHistogramLogInput) (*QueryHistogramLogOutput, error)
 
+	QueryHistogramLogWithContext(context.Context, *QueryHistogramLogInput) (*QueryHistogramLogOutput, error)
+
+	PutRepoConfig(*PutRepoConfigInput) error
+
+	PutRepoConfigWithContext(context.Context, *PutRepoConfigInput) error
+
+	GetRepoConfig(*GetRepoConfigInput) (*GetRepoConfigOutput, error)
+
+	GetRepoConfigWithContext(context.Context, *GetRepoConfigInput) (*GetRepoConfigOutput, error)
+
 	MakeToken(*base.TokenDesc) (string, error)
+
+	Close() error
 }
@@ -522,6 +522,19 @@ type PutRepoConfigInput struct {
 }
 
 func (r *PutRepoConfigInput) Validate() (err error) {
+	if err = validateRepoName(r.RepoName); err != nil {
+		return
+	}
+	if r.TimeFieldName == "" {
+		return reqerr.NewInvalidArgs("TimeFieldName", "time field name should not be empty")
+	}
+	matched, err := regexp.MatchString(schemaKeyPattern, r.TimeFieldName)
+	if err != nil {
+		return reqerr.NewInvalidArgs("TimeFieldName", err.Error())
+	}
+	if !matched {
+		return reqerr.NewInvalidArgs("TimeFieldName", fmt.Sprintf("invalid time field name: %s", r.TimeFieldName))
+	}
 	return nil
 }
 
@@ -213,3 +213,20 @@ func TestCheckRetention(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestPutRepoConfigInputValidate(t *testing.T) {
+	tests := []struct {
+		input *PutRepoConfigInput
+		valid bool
+	}{
+		{&PutRepoConfigInput{RepoName: "repo", TimeFieldName: "timestamp"}, true},
+		{&PutRepoConfigInput{RepoName: "repo"}, false},
+		{&PutRepoConfigInput{RepoName: "repo", TimeFieldName: "1time"}, false},
+		{&PutRepoConfigInput{TimeFieldName: "timestamp"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.input.Validate(); (err == nil) != tt.valid {
+			t.Errorf("validate %+v, expect valid %v, got err %v", tt.input, tt.valid, err)
+		}
+	}
+}
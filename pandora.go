@@ -0,0 +1,100 @@
+// Package pandora 提供同时访问pipeline、logdb和tsdb的Client，
+// 各个服务共用同一组凭证和同一个http.Transport
+package pandora
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/qiniu/pandora-go-sdk/base/config"
+	"github.com/qiniu/pandora-go-sdk/logdb"
+	"github.com/qiniu/pandora-go-sdk/pipeline"
+	"github.com/qiniu/pandora-go-sdk/tsdb"
+)
+
+// 没有设置endpoint时各个服务使用的默认地址
+const (
+	DefaultPipelineEndpoint = "https://pipeline.qiniu.com"
+	DefaultLogdbEndpoint    = "https://logdb.qiniu.com"
+	DefaultTsdbEndpoint     = "https://tsdb.qiniu.com"
+)
+
+type Client struct {
+	Config     *config.Config
+	HTTPClient *http.Client
+
+	transport *http.Transport //由Client创建，Close时需要关闭空闲连接
+	pipeline  pipeline.PipelineAPI
+	logdb     logdb.LogdbAPI
+	tsdb      tsdb.TsdbAPI
+}
+
+func NewConfig() *config.Config {
+	return config.NewConfig()
+}
+
+// New 创建pipeline、logdb和tsdb的client。c.HTTPClient为nil时创建一个所有服务共用的http.Client；
+// 服务的endpoint按PipelineEndpoint等、Endpoint、默认地址的顺序选取
+func New(c *config.Config) (client *Client, err error) {
+	cfg := *c
+	client = &Client{Config: &cfg}
+	if cfg.HTTPClient == nil {
+		client.transport = cfg.NewTransport()
+		cfg.HTTPClient = &http.Client{Transport: client.transport}
+	}
+	client.HTTPClient = cfg.HTTPClient
+
+	if client.pipeline, err = pipeline.New(withDefaultEndpoint(&cfg, config.ServicePipeline, DefaultPipelineEndpoint)); err != nil {
+		return nil, err
+	}
+	logdbClient, err := logdb.New(withDefaultEndpoint(&cfg, config.ServiceLogdb, DefaultLogdbEndpoint))
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	client.logdb = logdbClient
+	tsdbClient, err := tsdb.New(withDefaultEndpoint(&cfg, config.ServiceTsdb, DefaultTsdbEndpoint))
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	client.tsdb = tsdbClient
+	return client, nil
+}
+
+func withDefaultEndpoint(c *config.Config, service, endpoint string) *config.Config {
+	c = c.ForService(service)
+	if c.Endpoint != "" {
+		return c
+	}
+	cp := *c
+	cp.Endpoint = endpoint
+	return &cp
+}
+
+func (c *Client) Pipeline() pipeline.PipelineAPI {
+	return c.pipeline
+}
+
+func (c *Client) Logdb() logdb.LogdbAPI {
+	return c.logdb
+}
+
+func (c *Client) Tsdb() tsdb.TsdbAPI {
+	return c.tsdb
+}
+
+// Close 关闭各个服务的限速器，并关闭Client创建的http.Transport上的空闲连接，返回遇到的第一个错误
+func (c *Client) Close() (err error) {
+	for _, s := range []interface{}{c.pipeline, c.logdb, c.tsdb} {
+		if closer, ok := s.(io.Closer); ok {
+			if e := closer.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	if c.transport != nil {
+		c.transport.CloseIdleConnections()
+	}
+	return
+}
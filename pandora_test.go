@@ -0,0 +1,88 @@
+package pandora_test
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+
+	pandora "github.com/qiniu/pandora-go-sdk"
+	"github.com/qiniu/pandora-go-sdk/base"
+	"github.com/qiniu/pandora-go-sdk/logdb"
+	"github.com/qiniu/pandora-go-sdk/pandoratest"
+	"github.com/qiniu/pandora-go-sdk/pipeline"
+	"github.com/qiniu/pandora-go-sdk/tsdb"
+)
+
+func TestClient(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	cfg := s.NewConfig().WithLogger(&base.DefaultLogger{Logger: log.New(ioutil.Discard, "", 0)})
+	client, err := pandora.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if cfg.HTTPClient != nil {
+		t.Error("New should not modify the given config")
+	}
+
+	if err = client.Pipeline().CreateRepo(&pipeline.CreateRepoInput{
+		RepoName: "repo",
+		Region:   "nb",
+		Schema:   []pipeline.RepoSchemaEntry{{Key: "f1", ValueType: "string"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Logdb().CreateRepo(&logdb.CreateRepoInput{
+		RepoName:  "repo",
+		Region:    "nb",
+		Retention: "3d",
+		Schema:    []logdb.RepoSchemaEntry{{Key: "f1", ValueType: "string"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Tsdb().CreateRepo(&tsdb.CreateRepoInput{RepoName: "repo", Region: "nb"}); err != nil {
+		t.Fatal(err)
+	}
+
+	httpClients := []interface{}{
+		client.Pipeline().(*pipeline.Pipeline).HTTPClient,
+		client.Logdb().(*logdb.Logdb).HTTPClient,
+		client.Tsdb().(*tsdb.Tsdb).HTTPClient,
+	}
+	for _, c := range httpClients {
+		if c != client.HTTPClient {
+			t.Fatal("all services should share the same http client")
+		}
+	}
+	if err = client.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientEndpoints(t *testing.T) {
+	cfg := pandora.NewConfig().WithAccessKeySecretKey("ak", "sk")
+	cfg.LogdbEndpoint = "http://127.0.0.1:9999"
+	client, err := pandora.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	endpoints := map[string]string{
+		pandora.DefaultPipelineEndpoint: client.Pipeline().(*pipeline.Pipeline).Config.Endpoint,
+		"http://127.0.0.1:9999":         client.Logdb().(*logdb.Logdb).Config.Endpoint,
+		pandora.DefaultTsdbEndpoint:     client.Tsdb().(*tsdb.Tsdb).Config.Endpoint,
+	}
+	for expect, got := range endpoints {
+		if got != expect {
+			t.Errorf("expect endpoint %s, got %s", expect, got)
+		}
+	}
+
+	cfg.TsdbEndpoint = "tsdb.qiniu.com"
+	if _, err = pandora.New(cfg); err == nil {
+		t.Error("expect error for invalid endpoint")
+	}
+}
@@ -0,0 +1,299 @@
+package pandoratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type logdbSchemaEntry struct {
+	Key       string                 `json:"key"`
+	ValueType string                 `json:"valtype"`
+	Analyzer  string                 `json:"analyzer,omitempty"`
+	Primary   bool                   `json:"primary,omitempty"`
+	Schemas   []logdbSchemaEntry     `json:"nested,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+}
+
+type logdbRepo struct {
+	Region     string             `json:"region"`
+	Retention  string             `json:"retention"`
+	Schema     []logdbSchemaEntry `json:"schema"`
+	CreateTime string             `json:"createTime"`
+	UpdateTime string             `json:"updateTime"`
+
+	logs          []map[string]interface{}
+	timeFieldName string
+}
+
+type logdbState struct {
+	repos map[string]*logdbRepo
+}
+
+func newLogdbState() *logdbState {
+	return &logdbState{repos: map[string]*logdbRepo{}}
+}
+
+// Logs 返回写入logdb repo的所有日志
+func (s *Server) Logs(repoName string) []map[string]interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	repo, ok := s.logdb.repos[repoName]
+	if !ok {
+		return nil
+	}
+	return append([]map[string]interface{}(nil), repo.logs...)
+}
+
+func (s *Server) serveLogdb(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	if len(parts) == 0 || parts[0] != "repos" {
+		writeNotFound(w)
+		return
+	}
+	l := s.logdb
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		names := make([]string, 0, len(l.repos))
+		for name := range l.repos {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		repos := []map[string]string{}
+		for _, name := range names {
+			repo := l.repos[name]
+			repos = append(repos, map[string]string{
+				"name":       name,
+				"region":     repo.Region,
+				"retention":  repo.Retention,
+				"createTime": repo.CreateTime,
+				"updateTime": repo.UpdateTime,
+			})
+		}
+		writeJSON(w, map[string]interface{}{"repos": repos})
+		return
+	}
+
+	name := parts[1]
+	repo, exists := l.repos[name]
+	if len(parts) > 2 {
+		if !exists {
+			writeError(w, http.StatusNotFound, "E8111: repo not found")
+			return
+		}
+		if len(parts) != 3 {
+			writeNotFound(w)
+			return
+		}
+		switch {
+		case parts[2] == "data" && r.Method == http.MethodPost:
+			s.sendLog(w, r, repo, body)
+		case parts[2] == "search" && r.Method == http.MethodGet:
+			s.queryLog(w, r, repo)
+		case parts[2] == "histogram" && r.Method == http.MethodGet:
+			s.queryHistogram(w, r, repo)
+		case parts[2] == "config":
+			s.serveRepoConfig(w, r, repo, body)
+		default:
+			writeNotFound(w)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "E8112: repo already exists")
+			return
+		}
+		repo = &logdbRepo{}
+		if err := decodeBody(body, repo); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		repo.CreateTime, repo.UpdateTime = now(), now()
+		l.repos[name] = repo
+		writeOK(w)
+	case http.MethodPut:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E8111: repo not found")
+			return
+		}
+		update := &logdbRepo{}
+		if err := decodeBody(body, update); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		repo.Retention, repo.Schema, repo.UpdateTime = update.Retention, update.Schema, now()
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E8111: repo not found")
+			return
+		}
+		writeJSON(w, repo)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E8111: repo not found")
+			return
+		}
+		delete(l.repos, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveRepoConfig(w http.ResponseWriter, r *http.Request, repo *logdbRepo, body []byte) {
+	switch r.Method {
+	case http.MethodPut:
+		var config struct {
+			TimeFieldName string `json:"timeFieldName"`
+		}
+		if err := decodeBody(body, &config); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var found bool
+		for _, e := range repo.Schema {
+			if e.Key == config.TimeFieldName && e.ValueType == "date" {
+				found = true
+			}
+		}
+		if !found {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("time field %s should be a date field in repo schema", config.TimeFieldName))
+			return
+		}
+		repo.timeFieldName = config.TimeFieldName
+		writeOK(w)
+	case http.MethodGet:
+		writeJSON(w, map[string]string{"timeFieldName": repo.timeFieldName})
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) sendLog(w http.ResponseWriter, r *http.Request, repo *logdbRepo, body []byte) {
+	var logs []map[string]interface{}
+	if err := decodeBody(body, &logs); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	omitInvalidLog, _ := strconv.ParseBool(r.URL.Query().Get("omitInvalidLog"))
+	fields := map[string]bool{}
+	for _, e := range repo.Schema {
+		fields[e.Key] = true
+	}
+	valid := make([]map[string]interface{}, 0, len(logs))
+	for _, log := range logs {
+		var unknown string
+		for k := range log {
+			if !fields[k] {
+				unknown = k
+				break
+			}
+		}
+		if unknown == "" {
+			valid = append(valid, log)
+			continue
+		}
+		if !omitInvalidLog {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("E8104: field %s not in repo schema", unknown))
+			return
+		}
+	}
+	repo.logs = append(repo.logs, valid...)
+	writeJSON(w, map[string]int{
+		"success": len(valid),
+		"failed":  len(logs) - len(valid),
+		"total":   len(logs),
+	})
+}
+
+// matchLog 支持空查询或"*"匹配全部，"key:value"按字段精确匹配，其他情况在所有字段中做子串匹配
+func matchLog(log map[string]interface{}, q string) bool {
+	if q == "" || q == "*" {
+		return true
+	}
+	if idx := strings.Index(q, ":"); idx > 0 {
+		v, ok := log[q[:idx]]
+		return ok && fmt.Sprint(v) == q[idx+1:]
+	}
+	for _, v := range log {
+		if strings.Contains(fmt.Sprint(v), q) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) queryLog(w http.ResponseWriter, r *http.Request, repo *logdbRepo) {
+	query := r.URL.Query()
+	from, _ := strconv.Atoi(query.Get("from"))
+	size, _ := strconv.Atoi(query.Get("size"))
+	matched := []map[string]interface{}{}
+	for _, log := range repo.logs {
+		if matchLog(log, query.Get("q")) {
+			matched = append(matched, log)
+		}
+	}
+	data := []map[string]interface{}{}
+	if from < len(matched) {
+		end := len(matched)
+		if size > 0 && from+size < end {
+			end = from + size
+		}
+		data = matched[from:end]
+	}
+	writeJSON(w, map[string]interface{}{
+		"total":          len(matched),
+		"partialSuccess": false,
+		"data":           data,
+	})
+}
+
+// queryHistogram 把field字段(毫秒时间戳)落在[from, to)内的日志放入单个bucket中
+func (s *Server) queryHistogram(w http.ResponseWriter, r *http.Request, repo *logdbRepo) {
+	query := r.URL.Query()
+	from, _ := strconv.ParseInt(query.Get("from"), 10, 64)
+	to, _ := strconv.ParseInt(query.Get("to"), 10, 64)
+	field := query.Get("field")
+	var count int64
+	for _, log := range repo.logs {
+		if !matchLog(log, query.Get("q")) {
+			continue
+		}
+		ts, ok := toInt64(log[field])
+		if ok && ts >= from && (to == 0 || ts < to) {
+			count++
+		}
+	}
+	buckets := []map[string]int64{}
+	if count > 0 {
+		buckets = append(buckets, map[string]int64{"key": from, "count": count})
+	}
+	writeJSON(w, map[string]interface{}{
+		"total":          count,
+		"partialSuccess": false,
+		"buckets":        buckets,
+	})
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return int64(t), true
+	case json.Number:
+		i, err := t.Int64()
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(t, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,803 @@
+package pandoratest
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var pipelineNamePattern = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]{0,127}$")
+
+type pipelineSchemaEntry struct {
+	Key       string                `json:"key"`
+	ValueType string                `json:"valtype"`
+	Required  bool                  `json:"required"`
+	ElemType  string                `json:"elemtype,omitempty"`
+	Schema    []pipelineSchemaEntry `json:"schema,omitempty"`
+}
+
+type pipelineRepo struct {
+	Region      string                `json:"region"`
+	Schema      []pipelineSchemaEntry `json:"schema"`
+	GroupName   string                `json:"group"`
+	DerivedFrom string                `json:"derivedFrom"`
+
+	data       []string
+	transforms map[string]object
+	exports    map[string]object
+}
+
+type pipelineJob struct {
+	object
+	history   []object
+	exports   map[string]object
+	nextRunId int64
+}
+
+type pipelineState struct {
+	groups      map[string]object
+	repos       map[string]*pipelineRepo
+	plugins     map[string]object
+	datasources map[string]object
+	jobs        map[string]*pipelineJob
+}
+
+func newPipelineState() *pipelineState {
+	return &pipelineState{
+		groups:      map[string]object{},
+		repos:       map[string]*pipelineRepo{},
+		plugins:     map[string]object{},
+		datasources: map[string]object{},
+		jobs:        map[string]*pipelineJob{},
+	}
+}
+
+// Data 返回写入pipeline repo的所有数据行，每行为"key=value\tkey=value"格式
+func (s *Server) Data(repoName string) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	repo, ok := s.pipeline.repos[repoName]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), repo.data...)
+}
+
+func (s *Server) servePipeline(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	if len(parts) == 0 {
+		writeNotFound(w)
+		return
+	}
+	switch parts[0] {
+	case "groups":
+		s.serveGroups(w, r, parts[1:], body)
+	case "repos":
+		s.servePipelineRepos(w, r, parts[1:], body)
+	case "plugins":
+		s.servePlugins(w, r, parts[1:], body)
+	case "datasources":
+		s.serveDatasources(w, r, parts[1:], body)
+	case "jobs":
+		s.serveJobs(w, r, parts[1:], body)
+	case "verify":
+		s.serveVerify(w, r, parts[1:], body)
+	case "schemas":
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"schema": []pipelineSchemaEntry{}})
+	default:
+		writeNotFound(w)
+	}
+}
+
+func (s *Server) serveGroups(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	p := s.pipeline
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"groups": list(p.groups)})
+		return
+	}
+	name := parts[0]
+	group, exists := p.groups[name]
+	if len(parts) == 3 && parts[1] == "actions" && r.Method == http.MethodPost {
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18120: group not found")
+			return
+		}
+		container, _ := group["container"].(map[string]interface{})
+		switch parts[2] {
+		case "start":
+			if container != nil {
+				container["status"] = "Running"
+			}
+		case "stop":
+			if container != nil {
+				container["status"] = "Stopped"
+			}
+		default:
+			writeNotFound(w)
+			return
+		}
+		group["updateTime"] = now()
+		writeOK(w)
+		return
+	}
+	if len(parts) != 1 {
+		writeNotFound(w)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "E18218: group already exists")
+			return
+		}
+		obj, err := newObject(body, name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if container, ok := obj["container"].(map[string]interface{}); ok {
+			container["status"] = "Stopped"
+		}
+		obj["createTime"], obj["updateTime"] = now(), now()
+		p.groups[name] = obj
+		writeOK(w)
+	case http.MethodPut:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18120: group not found")
+			return
+		}
+		if err := group.merge(body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		group["updateTime"] = now()
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18120: group not found")
+			return
+		}
+		writeJSON(w, group)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18120: group not found")
+			return
+		}
+		delete(p.groups, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) servePipelineRepos(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	p := s.pipeline
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		repos := []map[string]string{}
+		for _, name := range sortedRepoNames(p.repos) {
+			repo := p.repos[name]
+			repos = append(repos, map[string]string{
+				"name":        name,
+				"region":      repo.Region,
+				"group":       repo.GroupName,
+				"derivedFrom": repo.DerivedFrom,
+			})
+		}
+		writeJSON(w, map[string]interface{}{"repos": repos})
+		return
+	}
+
+	name := parts[0]
+	repo, exists := p.repos[name]
+	if len(parts) > 1 {
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18102: repo not found")
+			return
+		}
+		switch parts[1] {
+		case "data":
+			if len(parts) != 2 || r.Method != http.MethodPost {
+				writeNotFound(w)
+				return
+			}
+			s.postData(w, repo, body)
+		case "transforms":
+			s.serveTransforms(w, r, name, repo, parts[2:], body)
+		case "exports":
+			s.serveExports(w, r, repo, parts[2:], body)
+		default:
+			writeNotFound(w)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "E18101: repo already exists")
+			return
+		}
+		if !pipelineNamePattern.MatchString(name) {
+			writeError(w, http.StatusBadRequest, "E18100: invalid repo name")
+			return
+		}
+		repo = &pipelineRepo{}
+		if err := decodeBody(body, repo); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(repo.Schema) == 0 {
+			writeError(w, http.StatusBadRequest, "E18104: schema should not be empty")
+			return
+		}
+		if repo.GroupName != "" {
+			if _, ok := p.groups[repo.GroupName]; !ok {
+				writeError(w, http.StatusNotFound, "E18120: group not found")
+				return
+			}
+		}
+		repo.transforms, repo.exports = map[string]object{}, map[string]object{}
+		p.repos[name] = repo
+		writeOK(w)
+	case http.MethodPut:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18102: repo not found")
+			return
+		}
+		var update struct {
+			Schema []pipelineSchemaEntry `json:"schema"`
+		}
+		if err := decodeBody(body, &update); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(update.Schema) == 0 {
+			writeError(w, http.StatusBadRequest, "E18104: schema should not be empty")
+			return
+		}
+		repo.Schema = update.Schema
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18102: repo not found")
+			return
+		}
+		writeJSON(w, repo)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18102: repo not found")
+			return
+		}
+		if len(repo.transforms) > 0 || len(repo.exports) > 0 {
+			writeError(w, http.StatusConflict, "E18112: repo has transforms or exports")
+			return
+		}
+		for _, other := range p.repos {
+			for _, t := range other.transforms {
+				if t["to"] == name {
+					writeError(w, http.StatusConflict, "E18112: repo is the destination of a transform")
+					return
+				}
+			}
+		}
+		delete(p.repos, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) postData(w http.ResponseWriter, repo *pipelineRepo, body []byte) {
+	if s.MaxBodySize > 0 && int64(len(body)) > s.MaxBodySize {
+		writeError(w, http.StatusRequestEntityTooLarge, "E18005: request entity too large")
+		return
+	}
+	if len(body) == 0 {
+		writeOK(w)
+		return
+	}
+	fields := map[string]pipelineSchemaEntry{}
+	for _, e := range repo.Schema {
+		fields[e.Key] = e
+	}
+	lines := strings.Split(string(body), "\n")
+	for i, line := range lines {
+		seen := map[string]bool{}
+		for _, kv := range strings.Split(line, "\t") {
+			idx := strings.Index(kv, "=")
+			if idx <= 0 {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("E18110: invalid data format at line %d", i+1))
+				return
+			}
+			key := kv[:idx]
+			if _, ok := fields[key]; !ok {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("E18111: field %s not in repo schema", key))
+				return
+			}
+			seen[key] = true
+		}
+		for _, e := range repo.Schema {
+			if e.Required && !seen[e.Key] {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("E18107: required field %s missing at line %d", e.Key, i+1))
+				return
+			}
+		}
+	}
+	repo.data = append(repo.data, lines...)
+	writeOK(w)
+}
+
+func (s *Server) serveTransforms(w http.ResponseWriter, r *http.Request, srcName string, src *pipelineRepo, parts []string, body []byte) {
+	p := s.pipeline
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"transforms": list(src.transforms)})
+		return
+	}
+	name := parts[0]
+	transform, exists := src.transforms[name]
+	if len(parts) == 3 && parts[1] == "to" && r.Method == http.MethodPost {
+		destName := parts[2]
+		if exists {
+			writeError(w, http.StatusConflict, "E18201: transform already exists")
+			return
+		}
+		var spec map[string]interface{}
+		if err := decodeBody(body, &spec); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !validTransformSpec(spec) {
+			writeError(w, http.StatusBadRequest, "E18207: invalid transform spec")
+			return
+		}
+		if _, ok := p.repos[destName]; !ok {
+			p.repos[destName] = &pipelineRepo{
+				Region:      src.Region,
+				Schema:      deriveSchema(src.Schema, spec),
+				GroupName:   src.GroupName,
+				DerivedFrom: srcName,
+				transforms:  map[string]object{},
+				exports:     map[string]object{},
+			}
+		}
+		src.transforms[name] = object{"name": name, "to": destName, "spec": spec}
+		writeOK(w)
+		return
+	}
+	if len(parts) != 1 {
+		writeNotFound(w)
+		return
+	}
+	if !exists {
+		writeError(w, http.StatusNotFound, "E18202: transform not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		var spec map[string]interface{}
+		if err := decodeBody(body, &spec); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		transform["spec"] = spec
+		writeOK(w)
+	case http.MethodGet:
+		writeJSON(w, transform)
+	case http.MethodDelete:
+		delete(src.transforms, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// deriveSchema 计算transform目标repo的schema，plugin指定了输出字段时使用plugin的输出，否则沿用源repo的schema
+func deriveSchema(src []pipelineSchemaEntry, spec map[string]interface{}) []pipelineSchemaEntry {
+	plugin, ok := spec["plugin"].(map[string]interface{})
+	if !ok {
+		return append([]pipelineSchemaEntry(nil), src...)
+	}
+	outputs, _ := plugin["output"].([]interface{})
+	schema := make([]pipelineSchemaEntry, 0, len(outputs))
+	for _, o := range outputs {
+		entry, _ := o.(map[string]interface{})
+		name, _ := entry["name"].(string)
+		typ, _ := entry["type"].(string)
+		if typ == "" {
+			typ = "string"
+		}
+		schema = append(schema, pipelineSchemaEntry{Key: name, ValueType: typ})
+	}
+	return schema
+}
+
+func validTransformSpec(spec map[string]interface{}) bool {
+	return spec["mode"] != nil || spec["code"] != nil || spec["plugin"] != nil
+}
+
+// serveVerify 校验transform和export的spec，transform会返回目标repo的schema
+func (s *Server) serveVerify(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	if len(parts) != 1 {
+		writeNotFound(w)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+	var input struct {
+		Schema []pipelineSchemaEntry  `json:"schema"`
+		Type   string                 `json:"type"`
+		Spec   map[string]interface{} `json:"spec"`
+	}
+	if err := decodeBody(body, &input); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(input.Schema) == 0 {
+		writeError(w, http.StatusBadRequest, "E18104: schema should not be empty")
+		return
+	}
+	switch parts[0] {
+	case "transform":
+		if !validTransformSpec(input.Spec) {
+			writeError(w, http.StatusBadRequest, "E18207: invalid transform spec")
+			return
+		}
+		writeJSON(w, map[string]interface{}{"schema": deriveSchema(input.Schema, input.Spec)})
+	case "export":
+		if !exportTypes[input.Type] || input.Spec == nil {
+			writeError(w, http.StatusBadRequest, "E18303: invalid export spec")
+			return
+		}
+		writeOK(w)
+	default:
+		writeNotFound(w)
+	}
+}
+
+var exportTypes = map[string]bool{"tsdb": true, "mongo": true, "logdb": true, "kodo": true, "http": true}
+
+func (s *Server) serveExports(w http.ResponseWriter, r *http.Request, repo *pipelineRepo, parts []string, body []byte) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"exports": list(repo.exports)})
+		return
+	}
+	if len(parts) != 1 {
+		writeNotFound(w)
+		return
+	}
+	name := parts[0]
+	export, exists := repo.exports[name]
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "E18301: export already exists")
+			return
+		}
+		obj, err := newObject(body, name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if typ, _ := obj["type"].(string); !exportTypes[typ] || obj["spec"] == nil {
+			writeError(w, http.StatusBadRequest, "E18303: invalid export spec")
+			return
+		}
+		repo.exports[name] = obj
+		writeOK(w)
+	case http.MethodPut:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18302: export not found")
+			return
+		}
+		if err := export.merge(body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18302: export not found")
+			return
+		}
+		writeJSON(w, export)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18302: export not found")
+			return
+		}
+		delete(repo.exports, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) servePlugins(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	p := s.pipeline
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"plugins": list(p.plugins)})
+		return
+	}
+	if len(parts) != 1 {
+		writeNotFound(w)
+		return
+	}
+	name := parts[0]
+	plugin, exists := p.plugins[name]
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "E18217: plugin already exists")
+			return
+		}
+		p.plugins[name] = object{"name": name, "createTime": now()}
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18216: plugin not found")
+			return
+		}
+		writeJSON(w, plugin)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E18216: plugin not found")
+			return
+		}
+		delete(p.plugins, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveDatasources(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	p := s.pipeline
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"datasources": list(p.datasources)})
+		return
+	}
+	if len(parts) != 1 {
+		writeNotFound(w)
+		return
+	}
+	name := parts[0]
+	datasource, exists := p.datasources[name]
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "datasource already exists")
+			return
+		}
+		obj, err := newObject(body, name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		p.datasources[name] = obj
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "datasource not found")
+			return
+		}
+		writeJSON(w, datasource)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "datasource not found")
+			return
+		}
+		delete(p.datasources, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveJobs(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	p := s.pipeline
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		srcJob, srcDatasource := r.URL.Query().Get("srcJob"), r.URL.Query().Get("srcDatasource")
+		jobs := []object{}
+		for _, name := range sortedJobNames(p.jobs) {
+			job := p.jobs[name]
+			if (srcJob == "" || job.hasSrc(srcJob)) && (srcDatasource == "" || job.hasSrc(srcDatasource)) {
+				jobs = append(jobs, job.object)
+			}
+		}
+		writeJSON(w, map[string]interface{}{"jobs": jobs})
+		return
+	}
+
+	name := parts[0]
+	job, exists := p.jobs[name]
+	if len(parts) > 1 {
+		if !exists {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		switch {
+		case len(parts) == 3 && parts[1] == "actions" && r.Method == http.MethodPost:
+			s.jobAction(w, job, parts[2])
+		case len(parts) == 2 && parts[1] == "history" && r.Method == http.MethodGet:
+			history := make([]object, len(job.history))
+			for i := range job.history {
+				history[len(history)-1-i] = job.history[i]
+			}
+			writeJSON(w, map[string]interface{}{"total": len(history), "history": history})
+		case parts[1] == "exports":
+			s.serveJobExports(w, r, job, parts[2:], body)
+		default:
+			writeNotFound(w)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "job already exists")
+			return
+		}
+		obj, err := newObject(body, name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		p.jobs[name] = &pipelineJob{object: obj, exports: map[string]object{}}
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		writeJSON(w, job.object)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		delete(p.jobs, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (j *pipelineJob) hasSrc(name string) bool {
+	srcs, _ := j.object["srcs"].([]interface{})
+	for _, src := range srcs {
+		if m, ok := src.(map[string]interface{}); ok && m["name"] == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) jobAction(w http.ResponseWriter, job *pipelineJob, action string) {
+	switch action {
+	case "start":
+		job.nextRunId++
+		job.history = append(job.history, object{
+			"id":        job.nextRunId,
+			"startTime": now(),
+			"status":    "Running",
+		})
+	case "stop":
+		if n := len(job.history); n > 0 && job.history[n-1]["status"] == "Running" {
+			job.history[n-1]["status"] = "Canceled"
+			job.history[n-1]["endTime"] = now()
+		}
+	default:
+		writeNotFound(w)
+		return
+	}
+	writeOK(w)
+}
+
+func (s *Server) serveJobExports(w http.ResponseWriter, r *http.Request, job *pipelineJob, parts []string, body []byte) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"exports": list(job.exports)})
+		return
+	}
+	if len(parts) != 1 {
+		writeNotFound(w)
+		return
+	}
+	name := parts[0]
+	export, exists := job.exports[name]
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "job export already exists")
+			return
+		}
+		obj, err := newObject(body, name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		job.exports[name] = obj
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "job export not found")
+			return
+		}
+		writeJSON(w, export)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "job export not found")
+			return
+		}
+		delete(job.exports, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func sortedRepoNames(repos map[string]*pipelineRepo) []string {
+	names := make([]string, 0, len(repos))
+	for name := range repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedJobNames(jobs map[string]*pipelineJob) []string {
+	names := make([]string, 0, len(jobs))
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(objects map[string]object) []string {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,254 @@
+// Package pandoratest 提供一个内存中的Pandora服务端，实现了pipeline(/v2)、logdb(/v5)
+// 和tsdb(/v4)的主要接口，用于在没有网络的环境下测试基于SDK的代码。
+package pandoratest
+
+import (
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+	"github.com/qiniu/pandora-go-sdk/base/config"
+)
+
+const (
+	DefaultAccessKey = "pandoratest-ak"
+	DefaultSecretKey = "pandoratest-sk"
+
+	// 与服务端一致，签名中的Date与服务端时间相差不能超过15分钟
+	maxDateSkew = 15 * time.Minute
+)
+
+// Server 是一个基于httptest.Server的Pandora服务端，所有状态都保存在内存中，
+// 可以被多个client并发访问。
+type Server struct {
+	*httptest.Server
+
+	AccessKey string
+	SecretKey string
+
+	// MaxBodySize 大于0时，超过该大小的写数据请求会返回E18005
+	MaxBodySize int64
+
+	// ClockOffset 是服务端时间与本地时间的差值，用于模拟客户端时钟不准，响应的Date头和签名校验都使用偏移之后的时间
+	ClockOffset time.Duration
+
+	lock     sync.Mutex
+	reqId    int64
+	pipeline *pipelineState
+	logdb    *logdbState
+	tsdb     *tsdbState
+}
+
+// NewServer 启动一个使用DefaultAccessKey/DefaultSecretKey鉴权的服务端
+func NewServer() *Server {
+	return NewServerWithCredentials(DefaultAccessKey, DefaultSecretKey)
+}
+
+func NewServerWithCredentials(ak, sk string) *Server {
+	s := &Server{
+		AccessKey: ak,
+		SecretKey: sk,
+		pipeline:  newPipelineState(),
+		logdb:     newLogdbState(),
+		tsdb:      newTsdbState(),
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// NewConfig 返回指向当前服务端并且带有正确AK/SK的配置
+func (s *Server) NewConfig() *config.Config {
+	return config.NewConfig().
+		WithEndpoint(s.URL).
+		WithAccessKeySecretKey(s.AccessKey, s.SecretKey)
+}
+
+// Reset 清空服务端保存的所有资源
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pipeline = newPipelineState()
+	s.logdb = newLogdbState()
+	s.tsdb = newTsdbState()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Date", s.serverTime().UTC().Format(http.TimeFormat))
+	w.Header().Set(base.HTTPHeaderRequestId, fmt.Sprintf("pandoratest-%d", atomic.AddInt64(&s.reqId, 1)))
+	if err := s.authorize(r); err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("read body failed: %v", err))
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch parts[0] {
+	case "v2":
+		s.servePipeline(w, r, parts[1:], body)
+	case "v5":
+		s.serveLogdb(w, r, parts[1:], body)
+	case "v4":
+		s.serveTsdb(w, r, parts[1:], body)
+	default:
+		writeNotFound(w)
+	}
+}
+
+func (s *Server) serverTime() time.Time {
+	return time.Now().Add(s.ClockOffset)
+}
+
+func readBody(r *http.Request) (body []byte, err error) {
+	if r.Body == nil {
+		return
+	}
+	var reader io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		reader = gr
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// authorize 校验SDK通过base.Sign生成的AK/SK签名，或者通过MakeToken签发的token
+func (s *Server) authorize(r *http.Request) error {
+	auth := r.Header.Get(base.HTTPHeaderAuthorization)
+	if !strings.HasPrefix(auth, "Pandora ") {
+		return fmt.Errorf("missing authorization")
+	}
+	parts := strings.Split(strings.TrimPrefix(auth, "Pandora "), ":")
+	switch len(parts) {
+	case 2:
+		return s.verifySign(parts[0], parts[1], r)
+	case 3:
+		return s.verifyToken(parts[0], r)
+	}
+	return fmt.Errorf("malformed authorization")
+}
+
+func (s *Server) verifySign(ak, sign string, r *http.Request) error {
+	if ak != s.AccessKey {
+		return fmt.Errorf("unknown access key %s", ak)
+	}
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("invalid date header")
+	}
+	if skew := s.serverTime().Sub(date); skew > maxDateSkew || skew < -maxDateSkew {
+		return fmt.Errorf("date header is too far from server time")
+	}
+
+	h := hmac.New(sha1.New, []byte(s.SecretKey))
+	io.WriteString(h, fmt.Sprintf("%s\n%s\n%s\n%s\n",
+		r.Method,
+		r.Header.Get(base.HTTPHeaderContentMD5),
+		r.Header.Get(base.HTTPHeaderContentType),
+		r.Header.Get("Date")))
+	io.WriteString(h, base.SignQiniuHeader(r.Header))
+	io.WriteString(h, base.SignQiniuResource(r.URL.Path, r.URL.Query()))
+	if base64.URLEncoding.EncodeToString(h.Sum(nil)) != sign {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func (s *Server) verifyToken(ak string, r *http.Request) error {
+	if ak != s.AccessKey {
+		return fmt.Errorf("unknown access key %s", ak)
+	}
+	return base.VerifyTokenAt(s.SecretKey, r.Header.Get(base.HTTPHeaderAuthorization), r, s.serverTime())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+	w.WriteHeader(http.StatusOK)
+	if v != nil {
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+func writeOK(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeError 返回与真实服务一致的错误格式，message以错误码开头，例如"E18102: repo not found"
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, "404 page not found")
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func decodeBody(body []byte, v interface{}) error {
+	if len(body) == 0 {
+		return fmt.Errorf("request body should not be empty")
+	}
+	return json.Unmarshal(body, v)
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// object 保存一个通过JSON创建的资源，读取时原样返回
+type object map[string]interface{}
+
+func newObject(body []byte, name string) (object, error) {
+	obj := object{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &obj); err != nil {
+			return nil, err
+		}
+	}
+	obj["name"] = name
+	return obj, nil
+}
+
+func (o object) merge(body []byte) error {
+	update := object{}
+	if err := json.Unmarshal(body, &update); err != nil {
+		return err
+	}
+	for k, v := range update {
+		o[k] = v
+	}
+	return nil
+}
+
+func list(objects map[string]object) []object {
+	out := make([]object, 0, len(objects))
+	for _, name := range sortedKeys(objects) {
+		out = append(out, objects[name])
+	}
+	return out
+}
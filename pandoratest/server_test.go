@@ -0,0 +1,528 @@
+package pandoratest_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+	"github.com/qiniu/pandora-go-sdk/base/config"
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+	"github.com/qiniu/pandora-go-sdk/logdb"
+	"github.com/qiniu/pandora-go-sdk/pandoratest"
+	"github.com/qiniu/pandora-go-sdk/pipeline"
+	"github.com/qiniu/pandora-go-sdk/tsdb"
+)
+
+func newConfig(s *pandoratest.Server) *config.Config {
+	return s.NewConfig().WithLogger(&base.DefaultLogger{Logger: log.New(ioutil.Discard, "", 0)})
+}
+
+func errorType(err error) int {
+	if e, ok := err.(*reqerr.RequestError); ok {
+		return e.ErrorType
+	}
+	return -1
+}
+
+func TestPipeline(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	client, err := pipeline.New(newConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.CreateRepo(&pipeline.CreateRepoInput{
+		RepoName: "repo",
+		Region:   "nb",
+		Schema: []pipeline.RepoSchemaEntry{
+			{Key: "f1", ValueType: "string", Required: true},
+			{Key: "f2", ValueType: "long"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.CreateRepo(&pipeline.CreateRepoInput{
+		RepoName: "repo",
+		Region:   "nb",
+		Schema:   []pipeline.RepoSchemaEntry{{Key: "f1", ValueType: "string"}},
+	})
+	if errorType(err) != reqerr.RepoAlreadyExistsError {
+		t.Fatalf("expect RepoAlreadyExistsError, got %v", err)
+	}
+	if _, err = client.GetRepo(&pipeline.GetRepoInput{RepoName: "none"}); errorType(err) != reqerr.NoSuchRepoError {
+		t.Fatalf("expect NoSuchRepoError, got %v", err)
+	}
+
+	err = client.PostData(&pipeline.PostDataInput{
+		RepoName: "repo",
+		Points: pipeline.Points{
+			{Fields: []pipeline.PointField{{Key: "f1", Value: "a"}, {Key: "f2", Value: 1}}},
+			{Fields: []pipeline.PointField{{Key: "f1", Value: "b"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"f1=a\tf2=1", "f1=b"}
+	if got := s.Data("repo"); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expect %v, got %v", exp, got)
+	}
+
+	err = client.PostData(&pipeline.PostDataInput{
+		RepoName: "repo",
+		Points:   pipeline.Points{{Fields: []pipeline.PointField{{Key: "f3", Value: "c"}}}},
+	})
+	if errorType(err) != reqerr.InvalidDataSchemaError {
+		t.Fatalf("expect InvalidDataSchemaError, got %v", err)
+	}
+
+	// 通过token访问
+	token, err := client.MakeToken(&base.TokenDesc{
+		Url:     "/v2/repos/repo",
+		Method:  "GET",
+		Expires: time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenClient, err := pipeline.New(newConfig(s).WithAccessKeySecretKey("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := &pipeline.GetRepoInput{RepoName: "repo"}
+	input.Token = token
+	repo, err := tokenClient.GetRepo(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Region != "nb" || len(repo.Schema) != 2 {
+		t.Fatalf("unexpected repo %+v", repo)
+	}
+	if _, err = tokenClient.GetRepo(&pipeline.GetRepoInput{RepoName: "repo", PipelineToken: pipeline.PipelineToken{Token: token + "x"}}); errorType(err) != reqerr.UnauthorizedError {
+		t.Fatalf("expect UnauthorizedError, got %v", err)
+	}
+
+	if err = client.DeleteRepo(&pipeline.DeleteRepoInput{RepoName: "repo"}); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := client.ListRepos(&pipeline.ListReposInput{}); err != nil || len(output.Repos) != 0 {
+		t.Fatalf("expect no repos, got %v %v", output, err)
+	}
+}
+
+func TestEnsureRepo(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	client, err := pipeline.New(newConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := &pipeline.CreateRepoInput{
+		RepoName: "repo",
+		Region:   "nb",
+		Schema:   []pipeline.RepoSchemaEntry{{Key: "f1", ValueType: "string"}},
+	}
+	output, err := client.EnsureRepo(input)
+	if err != nil || !output.Created {
+		t.Fatalf("expect repo created, got %+v %v", output, err)
+	}
+	input.Schema = append(input.Schema, pipeline.RepoSchemaEntry{Key: "f2", ValueType: "long"})
+	output, err = client.EnsureRepo(input)
+	if err != nil || output.Created || !output.Updated {
+		t.Fatalf("expect repo updated, got %+v %v", output, err)
+	}
+	repo, err := client.GetRepo(&pipeline.GetRepoInput{RepoName: "repo"})
+	if err != nil || len(repo.Schema) != 2 {
+		t.Fatalf("expect 2 fields, got %+v %v", repo, err)
+	}
+	input.Schema[1].ValueType = "string"
+	output, err = client.EnsureRepo(input)
+	if !errors.Is(err, reqerr.ErrSchemaConflict) || len(output.Diff.Conflicts) != 1 {
+		t.Fatalf("expect schema conflict, got %+v %v", output, err)
+	}
+
+	tsdbClient, err := tsdb.New(newConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tsdbInput := &tsdb.CreateRepoInput{RepoName: "repo", Region: "nb", Metadata: map[string]string{"k1": "v1"}}
+	if _, err = tsdbClient.EnsureRepo(tsdbInput); err != nil {
+		t.Fatal(err)
+	}
+	tsdbInput.Metadata["k2"] = "v2"
+	tsdbOutput, err := tsdbClient.EnsureRepo(tsdbInput)
+	if err != nil || !tsdbOutput.Updated {
+		t.Fatalf("expect repo updated, got %+v %v", tsdbOutput, err)
+	}
+	tsdbInput.Metadata["k1"] = "v3"
+	if _, err = tsdbClient.EnsureRepo(tsdbInput); !errors.Is(err, reqerr.ErrSchemaConflict) {
+		t.Fatalf("expect schema conflict, got %v", err)
+	}
+}
+
+func TestPipelineWrongSecretKey(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	client, err := pipeline.New(newConfig(s).WithAccessKeySecretKey(pandoratest.DefaultAccessKey, "wrong"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.ListRepos(&pipeline.ListReposInput{}); errorType(err) != reqerr.UnauthorizedError {
+		t.Fatalf("expect UnauthorizedError, got %v", err)
+	}
+}
+
+func TestPipelineClockSkew(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+	s.ClockOffset = 20 * time.Minute
+
+	cfg := newConfig(s)
+	client, err := pipeline.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.ListRepos(&pipeline.ListReposInput{}); err != nil {
+		t.Fatalf("expect request re-signed with corrected time, got %v", err)
+	}
+	if offset := cfg.Clock.Offset(); offset < 19*time.Minute || offset > 21*time.Minute {
+		t.Fatalf("unexpected clock offset %v", offset)
+	}
+	if _, err = client.ListRepos(&pipeline.ListReposInput{}); err != nil {
+		t.Fatal(err)
+	}
+
+	td := &base.TokenDesc{Url: "/v2/repos/repo/data", Method: base.MethodPost, Expires: time.Now().Add(10 * time.Minute).Unix()}
+	if _, err = client.MakeToken(td); err == nil {
+		t.Fatal("expect token already expired by server time")
+	}
+}
+
+func TestPipelineCredentialsRotation(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	creds := base.Credentials{AccessKey: pandoratest.DefaultAccessKey, SecretKey: "wrong"}
+	client, err := pipeline.New(newConfig(s).WithCredentialsProvider(base.CredentialsFunc(func() (base.Credentials, error) {
+		return creds, nil
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.ListRepos(&pipeline.ListReposInput{}); errorType(err) != reqerr.UnauthorizedError {
+		t.Fatalf("expect UnauthorizedError, got %v", err)
+	}
+	creds.SecretKey = pandoratest.DefaultSecretKey
+	if _, err = client.ListRepos(&pipeline.ListReposInput{}); err != nil {
+		t.Fatalf("expect rotated credentials to be used, got %v", err)
+	}
+}
+
+func TestTokenIssuer(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	client, err := pipeline.New(newConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.CreateRepo(&pipeline.CreateRepoInput{
+		RepoName: "repo",
+		Region:   "nb",
+		Schema:   []pipeline.RepoSchemaEntry{{Key: "f1", ValueType: "string"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := base.NewTokenIssuer(base.NewStaticCredentialsProvider(pandoratest.DefaultAccessKey, pandoratest.DefaultSecretKey))
+	issued, err := issuer.Issue(base.ScopePipelinePostData.Name, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, err := pipeline.New(newConfig(s).WithAccessKeySecretKey("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := &pipeline.PostDataInput{RepoName: "repo", Points: pipeline.Points{{Fields: []pipeline.PointField{{Key: "f1", Value: "a"}}}}}
+	input.Token = issued.Token
+	if err = device.PostData(input); err != nil {
+		t.Fatalf("post data with issued token failed, %v", err)
+	}
+	if _, err = device.GetRepo(&pipeline.GetRepoInput{RepoName: "repo", PipelineToken: pipeline.PipelineToken{Token: issued.Token}}); errorType(err) != reqerr.UnauthorizedError {
+		t.Fatalf("expect token scoped to post data, got %v", err)
+	}
+}
+
+func TestTokenExpiredByServerTime(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	client, err := pipeline.New(newConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.CreateRepo(&pipeline.CreateRepoInput{
+		RepoName: "repo",
+		Region:   "nb",
+		Schema:   []pipeline.RepoSchemaEntry{{Key: "f1", ValueType: "string"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	td := &base.TokenDesc{Url: "/v2/repos/repo/data", Method: base.MethodPost, ContentType: base.ContentTypeText, Expires: time.Now().Add(10 * time.Minute).Unix()}
+	token, err := base.MakeTokenInternal(pandoratest.DefaultAccessKey, pandoratest.DefaultSecretKey, td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, err := pipeline.New(newConfig(s).WithAccessKeySecretKey("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := &pipeline.PostDataInput{RepoName: "repo", Points: pipeline.Points{{Fields: []pipeline.PointField{{Key: "f1", Value: "a"}}}}}
+	input.Token = token
+	if err = device.PostData(input); err != nil {
+		t.Fatalf("post data with token failed, %v", err)
+	}
+	s.ClockOffset = 20 * time.Minute
+	if err = device.PostData(input); errorType(err) != reqerr.UnauthorizedError {
+		t.Fatalf("expect token expired by server time, got %v", err)
+	}
+}
+
+func TestPipelineVerify(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	client, err := pipeline.New(newConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := []pipeline.RepoSchemaEntry{{Key: "f1", ValueType: "string"}}
+	output, err := client.VerifyTransform(&pipeline.VerifyTransformInput{
+		Schema: schema,
+		Spec: &pipeline.TransformSpec{
+			Plugin: &pipeline.TransformPlugin{
+				Name:   "plugin",
+				Output: []pipeline.TransformPluginOutputEntry{{Name: "f2", Type: "long"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []pipeline.RepoSchemaEntry{{Key: "f2", ValueType: "long"}}
+	if !reflect.DeepEqual(output.Schema, exp) {
+		t.Fatalf("expect %v, got %v", exp, output.Schema)
+	}
+
+	err = client.VerifyExport(&pipeline.VerifyExportInput{
+		Schema: schema,
+		Spec: &pipeline.ExportTsdbSpec{
+			DestRepoName: "dest",
+			SeriesName:   "series",
+			Fields:       map[string]string{"f1": "#f1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.VerifyTransform(&pipeline.VerifyTransformInput{Schema: schema}); err == nil {
+		t.Fatal("expect error for nil spec")
+	}
+}
+
+func TestLogdb(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	client, err := logdb.New(newConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.CreateRepo(&logdb.CreateRepoInput{
+		RepoName:  "repo",
+		Region:    "nb",
+		Retention: "3d",
+		Schema: []logdb.RepoSchemaEntry{
+			{Key: "f1", ValueType: "string"},
+			{Key: "f2", ValueType: "long"},
+			{Key: "f3", ValueType: "date"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := client.SendLog(&logdb.SendLogInput{
+		RepoName: "repo",
+		Logs:     logdb.Logs{{"f1": "hello", "f2": 1}, {"f1": "world", "f2": 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Success != 2 || output.Total != 2 {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	_, err = client.SendLog(&logdb.SendLogInput{RepoName: "repo", Logs: logdb.Logs{{"f5": "x"}}})
+	if errorType(err) != reqerr.UnmatchedSchemaError {
+		t.Fatalf("expect UnmatchedSchemaError, got %v", err)
+	}
+	output, err = client.SendLog(&logdb.SendLogInput{RepoName: "repo", OmitInvalidLog: true, Logs: logdb.Logs{{"f5": "x"}}})
+	if err != nil || output.Failed != 1 {
+		t.Fatalf("expect 1 failed log, got %+v %v", output, err)
+	}
+
+	result, err := client.QueryLog(&logdb.QueryLogInput{RepoName: "repo", Query: "f1:world", Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 1 || len(result.Data) != 1 || result.Data[0]["f1"] != "world" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if len(s.Logs("repo")) != 2 {
+		t.Fatalf("expect 2 logs, got %v", s.Logs("repo"))
+	}
+
+	if err = client.PutRepoConfig(&logdb.PutRepoConfigInput{RepoName: "repo", TimeFieldName: "f1"}); err == nil {
+		t.Fatal("expect error for non date time field")
+	}
+	if err = client.PutRepoConfig(&logdb.PutRepoConfigInput{RepoName: "repo", TimeFieldName: "f3"}); err != nil {
+		t.Fatal(err)
+	}
+	config, err := client.GetRepoConfig(&logdb.GetRepoConfigInput{RepoName: "repo"})
+	if err != nil || config.TimeFieldName != "f3" {
+		t.Fatalf("expect time field f3, got %+v %v", config, err)
+	}
+
+	if _, err = client.GetRepo(&logdb.GetRepoInput{RepoName: "none"}); errorType(err) != reqerr.NoSuchRepoError {
+		t.Fatalf("expect NoSuchRepoError, got %v", err)
+	}
+}
+
+func TestTsdb(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	client, err := tsdb.New(newConfig(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.CreateRepo(&tsdb.CreateRepoInput{RepoName: "repo", Region: "nb"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.CreateSeries(&tsdb.CreateSeriesInput{RepoName: "repo", SeriesName: "cpu", Retention: "7d"}); err != nil {
+		t.Fatal(err)
+	}
+	err = client.CreateSeries(&tsdb.CreateSeriesInput{RepoName: "repo", SeriesName: "cpu", Retention: "7d"})
+	if errorType(err) != reqerr.SeriesAlreadyExistsError {
+		t.Fatalf("expect SeriesAlreadyExistsError, got %v", err)
+	}
+
+	err = client.PostPoints(&tsdb.PostPointsInput{
+		RepoName: "repo",
+		Points: tsdb.Points{
+			{SeriesName: "cpu", Tags: map[string]string{"host": "a b"}, Fields: map[string]interface{}{"usage": 0.5, "count": 3}, Time: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := s.Points("repo", "cpu")
+	if len(points) != 1 || points[0].Tags["host"] != "a b" || points[0].Fields["count"] != int64(3) || points[0].Time != 1 {
+		t.Fatalf("unexpected points %+v", points)
+	}
+
+	output, err := client.QueryPoints(&tsdb.QueryInput{RepoName: "repo", Sql: "select * from cpu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Results) != 1 || len(output.Results[0].Series) != 1 || len(output.Results[0].Series[0].Values) != 1 {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	if _, err = client.QueryPoints(&tsdb.QueryInput{RepoName: "repo", Sql: "show series"}); errorType(err) != reqerr.InvalidQuerySql {
+		t.Fatalf("expect InvalidQuerySql, got %v", err)
+	}
+	if _, err = client.GetRepo(&tsdb.GetRepoInput{RepoName: "none"}); errorType(err) != reqerr.NoSuchRepoError {
+		t.Fatalf("expect NoSuchRepoError, got %v", err)
+	}
+}
+
+func TestLogdbTsdbSharedConfig(t *testing.T) {
+	s := pandoratest.NewServer()
+	defer s.Close()
+
+	encodings := map[string]string{}
+	cfg := newConfig(s).WithGzipData(true).WithRequestRateLimit(100).WithFlowRateLimit(1024).WithInterceptors(
+		func(call *base.Call, next base.Handler) error {
+			encodings[call.Operation.Name] = call.HTTPRequest.Header.Get("Content-Encoding")
+			return next(call)
+		},
+	)
+	logdbClient, err := logdb.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logdbClient.Close()
+	tsdbClient, err := tsdb.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tsdbClient.Close()
+
+	err = logdbClient.CreateRepo(&logdb.CreateRepoInput{
+		RepoName:  "repo",
+		Region:    "nb",
+		Retention: "3d",
+		Schema:    []logdb.RepoSchemaEntry{{Key: "f1", ValueType: "string"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = logdbClient.SendLog(&logdb.SendLogInput{RepoName: "repo", Logs: logdb.Logs{{"f1": "hello"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if logs := s.Logs("repo"); len(logs) != 1 || logs[0]["f1"] != "hello" {
+		t.Fatalf("unexpected logs %v", logs)
+	}
+	if _, err = logdbClient.QueryLog(&logdb.QueryLogInput{RepoName: "repo", Query: "f1:hello", Size: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = tsdbClient.CreateRepo(&tsdb.CreateRepoInput{RepoName: "repo", Region: "nb"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = tsdbClient.CreateSeries(&tsdb.CreateSeriesInput{RepoName: "repo", SeriesName: "cpu", Retention: "7d"}); err != nil {
+		t.Fatal(err)
+	}
+	err = tsdbClient.PostPointsFromBytes(&tsdb.PostPointsFromBytesInput{RepoName: "repo", Buffer: []byte("cpu,host=a usage=0.5 1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points := s.Points("repo", "cpu"); len(points) != 1 || points[0].Tags["host"] != "a" {
+		t.Fatalf("unexpected points %+v", points)
+	}
+	if _, err = tsdbClient.QueryPoints(&tsdb.QueryInput{RepoName: "repo", Sql: "select * from cpu"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		base.OpSendLog:     "gzip",
+		base.OpWritePoints: "gzip",
+		base.OpQueryLog:    "",
+		base.OpQueryPoints: "",
+	}
+	for op, encoding := range expected {
+		if encodings[op] != encoding {
+			t.Errorf("expect %s Content-Encoding %q, got %q", op, encoding, encodings[op])
+		}
+	}
+}
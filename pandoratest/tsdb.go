@@ -0,0 +1,488 @@
+package pandoratest
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type tsdbSeries struct {
+	Retention  string            `json:"retention"`
+	Metadata   map[string]string `json:"metadata"`
+	CreateTime string            `json:"createTime"`
+}
+
+type tsdbView struct {
+	Sql        string            `json:"sql"`
+	Retention  string            `json:"retention"`
+	Metadata   map[string]string `json:"metadata"`
+	CreateTime string            `json:"createTime"`
+}
+
+type tsdbRepo struct {
+	Region     string            `json:"region"`
+	Metadata   map[string]string `json:"metadata"`
+	CreateTime string            `json:"createTime"`
+
+	series map[string]*tsdbSeries
+	views  map[string]*tsdbView
+	points map[string][]Point
+}
+
+type tsdbState struct {
+	repos map[string]*tsdbRepo
+}
+
+func newTsdbState() *tsdbState {
+	return &tsdbState{repos: map[string]*tsdbRepo{}}
+}
+
+// Point 是服务端从line protocol解析出的一个数据点，整数字段为int64，浮点字段为float64
+type Point struct {
+	SeriesName string
+	Tags       map[string]string
+	Fields     map[string]interface{}
+	Time       int64
+}
+
+// Points 返回写入tsdb repo中指定series的所有数据点
+func (s *Server) Points(repoName, seriesName string) []Point {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	repo, ok := s.tsdb.repos[repoName]
+	if !ok {
+		return nil
+	}
+	return append([]Point(nil), repo.points[seriesName]...)
+}
+
+func (s *Server) serveTsdb(w http.ResponseWriter, r *http.Request, parts []string, body []byte) {
+	if len(parts) == 0 || parts[0] != "repos" {
+		writeNotFound(w)
+		return
+	}
+	t := s.tsdb
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		repos := []map[string]interface{}{}
+		for _, name := range sortedTsdbRepoNames(t.repos) {
+			repo := t.repos[name]
+			repos = append(repos, map[string]interface{}{
+				"name":       name,
+				"region":     repo.Region,
+				"metadata":   repo.Metadata,
+				"createTime": repo.CreateTime,
+				"deleting":   "",
+			})
+		}
+		writeJSON(w, repos)
+		return
+	}
+
+	name := parts[1]
+	repo, exists := t.repos[name]
+	if len(parts) > 2 {
+		if !exists {
+			writeError(w, http.StatusNotFound, "E7100: repo not found")
+			return
+		}
+		switch parts[2] {
+		case "meta":
+			s.serveMetadata(w, r, &repo.Metadata, parts[3:], body)
+		case "series":
+			s.serveSeries(w, r, repo, parts[3:], body)
+		case "views":
+			s.serveViews(w, r, repo, parts[3:], body)
+		case "points":
+			if len(parts) != 3 || r.Method != http.MethodPost {
+				writeNotFound(w)
+				return
+			}
+			s.writePoints(w, repo, body)
+		case "query":
+			if len(parts) != 3 || r.Method != http.MethodPost {
+				writeNotFound(w)
+				return
+			}
+			s.queryPoints(w, repo, body)
+		default:
+			writeNotFound(w)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "E6102: repo already exists")
+			return
+		}
+		repo = &tsdbRepo{}
+		if err := decodeBody(body, repo); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		repo.CreateTime = now()
+		repo.series, repo.views, repo.points = map[string]*tsdbSeries{}, map[string]*tsdbView{}, map[string][]Point{}
+		t.repos[name] = repo
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E7100: repo not found")
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"name":       name,
+			"region":     repo.Region,
+			"metadata":   repo.Metadata,
+			"createTime": repo.CreateTime,
+			"deleting":   "",
+		})
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E7100: repo not found")
+			return
+		}
+		delete(t.repos, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+// serveMetadata 处理repo和series的meta接口，POST合并metadata，DELETE清空metadata
+func (s *Server) serveMetadata(w http.ResponseWriter, r *http.Request, metadata *map[string]string, parts []string, body []byte) {
+	if len(parts) != 0 {
+		writeNotFound(w)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		var update struct {
+			Metadata map[string]string `json:"metadata"`
+		}
+		if err := decodeBody(body, &update); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if *metadata == nil {
+			*metadata = map[string]string{}
+		}
+		for k, v := range update.Metadata {
+			(*metadata)[k] = v
+		}
+		writeOK(w)
+	case http.MethodDelete:
+		*metadata = nil
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveSeries(w http.ResponseWriter, r *http.Request, repo *tsdbRepo, parts []string, body []byte) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		names := make([]string, 0, len(repo.series))
+		for name := range repo.series {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		series := []map[string]interface{}{}
+		for _, name := range names {
+			ss := repo.series[name]
+			series = append(series, map[string]interface{}{
+				"name":       name,
+				"retention":  ss.Retention,
+				"metadata":   ss.Metadata,
+				"createTime": ss.CreateTime,
+				"type":       "normal",
+				"deleting":   "",
+			})
+		}
+		writeJSON(w, series)
+		return
+	}
+
+	name := parts[0]
+	series, exists := repo.series[name]
+	if len(parts) > 1 {
+		if !exists {
+			writeError(w, http.StatusNotFound, "E6303: series not found")
+			return
+		}
+		if parts[1] != "meta" {
+			writeNotFound(w)
+			return
+		}
+		s.serveMetadata(w, r, &series.Metadata, parts[2:], body)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "E6302: series already exists")
+			return
+		}
+		series = &tsdbSeries{}
+		if err := decodeBody(body, series); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		series.CreateTime = now()
+		repo.series[name] = series
+		writeOK(w)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E6303: series not found")
+			return
+		}
+		delete(repo.series, name)
+		delete(repo.points, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) serveViews(w http.ResponseWriter, r *http.Request, repo *tsdbRepo, parts []string, body []byte) {
+	if len(parts) == 0 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+		names := make([]string, 0, len(repo.views))
+		for name := range repo.views {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		views := []map[string]interface{}{}
+		for _, name := range names {
+			v := repo.views[name]
+			views = append(views, map[string]interface{}{
+				"name":       name,
+				"retention":  v.Retention,
+				"createTime": v.CreateTime,
+				"deleting":   "",
+			})
+		}
+		writeJSON(w, views)
+		return
+	}
+	if len(parts) != 1 {
+		writeNotFound(w)
+		return
+	}
+
+	name := parts[0]
+	view, exists := repo.views[name]
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			writeError(w, http.StatusConflict, "E6411: view already exists")
+			return
+		}
+		view = &tsdbView{}
+		if err := decodeBody(body, view); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		view.CreateTime = now()
+		repo.views[name] = view
+		writeOK(w)
+	case http.MethodGet:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E6410: view not found")
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"sql":        view.Sql,
+			"retention":  view.Retention,
+			"metadata":   view.Metadata,
+			"createTime": view.CreateTime,
+			"deleting":   "",
+		})
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "E6410: view not found")
+			return
+		}
+		delete(repo.views, name)
+		writeOK(w)
+	default:
+		writeMethodNotAllowed(w)
+	}
+}
+
+func (s *Server) writePoints(w http.ResponseWriter, repo *tsdbRepo, body []byte) {
+	var points []Point
+	for i, line := range strings.Split(string(body), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("E7101: invalid point at line %d: %v", i+1, err))
+			return
+		}
+		if _, ok := repo.series[p.SeriesName]; !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("E6303: series %s not found", p.SeriesName))
+			return
+		}
+		points = append(points, p)
+	}
+	for _, p := range points {
+		repo.points[p.SeriesName] = append(repo.points[p.SeriesName], p)
+	}
+	writeOK(w)
+}
+
+// splitUnescaped 按sep切分s，忽略反斜杠转义和双引号内的分隔符
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	var quoted bool
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+var unescaper = strings.NewReplacer(`\,`, ",", `\ `, " ", `\=`, "=", `\"`, `"`, `\\`, `\`)
+
+func parseLine(line string) (p Point, err error) {
+	sections := splitUnescaped(line, ' ')
+	if len(sections) < 2 || len(sections) > 3 {
+		return p, fmt.Errorf("expect 2 or 3 sections, got %d", len(sections))
+	}
+	key := splitUnescaped(sections[0], ',')
+	p.SeriesName = unescaper.Replace(key[0])
+	p.Tags = map[string]string{}
+	for _, tag := range key[1:] {
+		kv := splitUnescaped(tag, '=')
+		if len(kv) != 2 {
+			return p, fmt.Errorf("invalid tag %s", tag)
+		}
+		p.Tags[unescaper.Replace(kv[0])] = unescaper.Replace(kv[1])
+	}
+	p.Fields = map[string]interface{}{}
+	for _, field := range splitUnescaped(sections[1], ',') {
+		kv := splitUnescaped(field, '=')
+		if len(kv) != 2 {
+			return p, fmt.Errorf("invalid field %s", field)
+		}
+		v, err := parseFieldValue(kv[1])
+		if err != nil {
+			return p, err
+		}
+		p.Fields[unescaper.Replace(kv[0])] = v
+	}
+	if len(sections) == 3 {
+		if p.Time, err = strconv.ParseInt(sections[2], 10, 64); err != nil {
+			return p, fmt.Errorf("invalid timestamp %s", sections[2])
+		}
+	}
+	return p, nil
+}
+
+func parseFieldValue(v string) (interface{}, error) {
+	switch {
+	case len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"':
+		return unescaper.Replace(v[1 : len(v)-1]), nil
+	case strings.HasSuffix(v, "i"):
+		return strconv.ParseInt(strings.TrimSuffix(v, "i"), 10, 64)
+	case v == "t" || v == "T" || v == "true" || v == "True" || v == "TRUE":
+		return true, nil
+	case v == "f" || v == "F" || v == "false" || v == "False" || v == "FALSE":
+		return false, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+var selectFromPattern = regexp.MustCompile(`(?i)^\s*select\s+.+\s+from\s+"?([^\s";]+)"?`)
+
+// queryPoints 只支持"select ... from <series>"，不论选择了哪些列，都返回series中的全部数据
+func (s *Server) queryPoints(w http.ResponseWriter, repo *tsdbRepo, body []byte) {
+	var query struct {
+		Sql string `json:"sql"`
+	}
+	if err := decodeBody(body, &query); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	m := selectFromPattern.FindStringSubmatch(query.Sql)
+	if m == nil {
+		writeError(w, http.StatusBadRequest, "E7200: unsupported sql: "+query.Sql)
+		return
+	}
+	points, ok := repo.points[m[1]]
+	if _, exists := repo.series[m[1]]; !exists {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("E6303: series %s not found", m[1]))
+		return
+	}
+	result := map[string]interface{}{}
+	if ok && len(points) > 0 {
+		result["series"] = []map[string]interface{}{makeSerie(m[1], points)}
+	}
+	writeJSON(w, map[string]interface{}{"results": []interface{}{result}})
+}
+
+func makeSerie(name string, points []Point) map[string]interface{} {
+	keys := map[string]bool{}
+	for _, p := range points {
+		for k := range p.Tags {
+			keys[k] = true
+		}
+		for k := range p.Fields {
+			keys[k] = true
+		}
+	}
+	columns := make([]string, 0, len(keys)+1)
+	for k := range keys {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+	columns = append([]string{"time"}, columns...)
+
+	values := make([][]interface{}, 0, len(points))
+	for _, p := range points {
+		row := make([]interface{}, len(columns))
+		row[0] = p.Time
+		for i, c := range columns[1:] {
+			if v, ok := p.Fields[c]; ok {
+				row[i+1] = v
+			} else if v, ok := p.Tags[c]; ok {
+				row[i+1] = v
+			}
+		}
+		values = append(values, row)
+	}
+	return map[string]interface{}{"name": name, "columns": columns, "values": values}
+}
+
+func sortedTsdbRepoNames(repos map[string]*tsdbRepo) []string {
+	names := make([]string, 0, len(repos))
+	for name := range repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
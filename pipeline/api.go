@@ -1,16 +1,21 @@
 package pipeline
 
 import (
+	"context"
 	"net/url"
 	"os"
 
 	"github.com/qiniu/pandora-go-sdk/base"
 )
 
-func (c *Pipeline) CreateGroup(input *CreateGroupInput) (err error) {
+func (c *Pipeline) CreateGroup(input *CreateGroupInput) error {
+	return c.CreateGroupWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) CreateGroupWithContext(ctx context.Context, input *CreateGroupInput) (err error) {
 	op := c.newOperation(base.OpCreateGroup, input.GroupName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -18,10 +23,14 @@ func (c *Pipeline) CreateGroup(input *CreateGroupInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) UpdateGroup(input *UpdateGroupInput) (err error) {
+func (c *Pipeline) UpdateGroup(input *UpdateGroupInput) error {
+	return c.UpdateGroupWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) UpdateGroupWithContext(ctx context.Context, input *UpdateGroupInput) (err error) {
 	op := c.newOperation(base.OpUpdateGroup, input.GroupName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -29,47 +38,71 @@ func (c *Pipeline) UpdateGroup(input *UpdateGroupInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) StartGroupTask(input *StartGroupTaskInput) (err error) {
+func (c *Pipeline) StartGroupTask(input *StartGroupTaskInput) error {
+	return c.StartGroupTaskWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) StartGroupTaskWithContext(ctx context.Context, input *StartGroupTaskInput) (err error) {
 	op := c.newOperation(base.OpStartGroupTask, input.GroupName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) StopGroupTask(input *StopGroupTaskInput) (err error) {
+func (c *Pipeline) StopGroupTask(input *StopGroupTaskInput) error {
+	return c.StopGroupTaskWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) StopGroupTaskWithContext(ctx context.Context, input *StopGroupTaskInput) (err error) {
 	op := c.newOperation(base.OpStopGroupTask, input.GroupName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) ListGroups(input *ListGroupsInput) (output *ListGroupsOutput, err error) {
+func (c *Pipeline) ListGroups(input *ListGroupsInput) (*ListGroupsOutput, error) {
+	return c.ListGroupsWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) ListGroupsWithContext(ctx context.Context, input *ListGroupsInput) (output *ListGroupsOutput, err error) {
 	op := c.newOperation(base.OpListGroups)
 
 	output = &ListGroupsOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) GetGroup(input *GetGroupInput) (output *GetGroupOutput, err error) {
+func (c *Pipeline) GetGroup(input *GetGroupInput) (*GetGroupOutput, error) {
+	return c.GetGroupWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) GetGroupWithContext(ctx context.Context, input *GetGroupInput) (output *GetGroupOutput, err error) {
 	op := c.newOperation(base.OpGetGroup, input.GroupName)
 
 	output = &GetGroupOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) DeleteGroup(input *DeleteGroupInput) (err error) {
+func (c *Pipeline) DeleteGroup(input *DeleteGroupInput) error {
+	return c.DeleteGroupWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) DeleteGroupWithContext(ctx context.Context, input *DeleteGroupInput) (err error) {
 	op := c.newOperation(base.OpDeleteGroup, input.GroupName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) CreateRepo(input *CreateRepoInput) (err error) {
+func (c *Pipeline) CreateRepo(input *CreateRepoInput) error {
+	return c.CreateRepoWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) CreateRepoWithContext(ctx context.Context, input *CreateRepoInput) (err error) {
 	op := c.newOperation(base.OpCreateRepo, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -77,12 +110,16 @@ func (c *Pipeline) CreateRepo(input *CreateRepoInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) CreateRepoFromDSL(input *CreateRepoDSLInput) (err error) {
+func (c *Pipeline) CreateRepoFromDSL(input *CreateRepoDSLInput) error {
+	return c.CreateRepoFromDSLWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) CreateRepoFromDSLWithContext(ctx context.Context, input *CreateRepoDSLInput) (err error) {
 	schemas, err := toSchema(input.DSL, 0)
 	if err != nil {
 		return
 	}
-	return c.CreateRepo(&CreateRepoInput{
+	return c.CreateRepoWithContext(ctx, &CreateRepoInput{
 		PipelineToken: input.PipelineToken,
 		RepoName:      input.RepoName,
 		Region:        input.Region,
@@ -91,10 +128,14 @@ func (c *Pipeline) CreateRepoFromDSL(input *CreateRepoDSLInput) (err error) {
 	})
 }
 
-func (c *Pipeline) UpdateRepo(input *UpdateRepoInput) (err error) {
+func (c *Pipeline) UpdateRepo(input *UpdateRepoInput) error {
+	return c.UpdateRepoWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) UpdateRepoWithContext(ctx context.Context, input *UpdateRepoInput) (err error) {
 	op := c.newOperation(base.OpUpdateRepo, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -102,44 +143,76 @@ func (c *Pipeline) UpdateRepo(input *UpdateRepoInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) GetRepo(input *GetRepoInput) (output *GetRepoOutput, err error) {
+func (c *Pipeline) GetRepo(input *GetRepoInput) (*GetRepoOutput, error) {
+	return c.GetRepoWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) GetRepoWithContext(ctx context.Context, input *GetRepoInput) (output *GetRepoOutput, err error) {
 	op := c.newOperation(base.OpGetRepo, input.RepoName)
 
 	output = &GetRepoOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) ListRepos(input *ListReposInput) (output *ListReposOutput, err error) {
+func (c *Pipeline) ListRepos(input *ListReposInput) (*ListReposOutput, error) {
+	return c.ListReposWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) ListReposWithContext(ctx context.Context, input *ListReposInput) (output *ListReposOutput, err error) {
 	op := c.newOperation(base.OpListRepos)
 
 	output = &ListReposOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) DeleteRepo(input *DeleteRepoInput) (err error) {
+func (c *Pipeline) DeleteRepo(input *DeleteRepoInput) error {
+	return c.DeleteRepoWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) DeleteRepoWithContext(ctx context.Context, input *DeleteRepoInput) (err error) {
 	op := c.newOperation(base.OpDeleteRepo, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) PostData(input *PostDataInput) (err error) {
+func (c *Pipeline) PostData(input *PostDataInput) error {
+	return c.PostDataWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) PostDataWithContext(ctx context.Context, input *PostDataInput) (err error) {
+	var body []byte
+	if len(input.Schema) == 0 {
+		body = input.Points.Buffer()
+	} else {
+		encoder, err := NewPointEncoder(input.Schema)
+		if err != nil {
+			return err
+		}
+		if body, err = encoder.Encode(input.Points); err != nil {
+			return err
+		}
+	}
 	op := c.newOperation(base.OpPostData, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
-	req.SetBufferBody(input.Points.Buffer())
+	req := c.newRequest(ctx, op, input.Token, nil)
+	req.SetBufferBody(body)
 	req.SetHeader(base.HTTPHeaderContentType, base.ContentTypeText)
 	req.SetFlowLimiter(c.flowLimit)
 	req.SetReqLimiter(c.reqLimit)
 	return req.Send()
 }
 
-func (c *Pipeline) PostDataFromFile(input *PostDataFromFileInput) (err error) {
+func (c *Pipeline) PostDataFromFile(input *PostDataFromFileInput) error {
+	return c.PostDataFromFileWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) PostDataFromFileWithContext(ctx context.Context, input *PostDataFromFileInput) (err error) {
 	op := c.newOperation(base.OpPostData, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	file, err := os.Open(input.FilePath)
 	if err != nil {
 		return err
@@ -157,10 +230,14 @@ func (c *Pipeline) PostDataFromFile(input *PostDataFromFileInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) PostDataFromReader(input *PostDataFromReaderInput) (err error) {
+func (c *Pipeline) PostDataFromReader(input *PostDataFromReaderInput) error {
+	return c.PostDataFromReaderWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) PostDataFromReaderWithContext(ctx context.Context, input *PostDataFromReaderInput) (err error) {
 	op := c.newOperation(base.OpPostData, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	req.SetReaderBody(input.Reader)
 	req.SetBodyLength(input.BodyLength)
 	req.SetHeader(base.HTTPHeaderContentType, base.ContentTypeText)
@@ -169,10 +246,14 @@ func (c *Pipeline) PostDataFromReader(input *PostDataFromReaderInput) (err error
 	return req.Send()
 }
 
-func (c *Pipeline) PostDataFromBytes(input *PostDataFromBytesInput) (err error) {
+func (c *Pipeline) PostDataFromBytes(input *PostDataFromBytesInput) error {
+	return c.PostDataFromBytesWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) PostDataFromBytesWithContext(ctx context.Context, input *PostDataFromBytesInput) (err error) {
 	op := c.newOperation(base.OpPostData, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	req.SetBufferBody(input.Buffer)
 	req.SetHeader(base.HTTPHeaderContentType, base.ContentTypeText)
 	req.SetFlowLimiter(c.flowLimit)
@@ -180,20 +261,28 @@ func (c *Pipeline) PostDataFromBytes(input *PostDataFromBytesInput) (err error)
 	return req.Send()
 }
 
-func (c *Pipeline) UploadPlugin(input *UploadPluginInput) (err error) {
+func (c *Pipeline) UploadPlugin(input *UploadPluginInput) error {
+	return c.UploadPluginWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) UploadPluginWithContext(ctx context.Context, input *UploadPluginInput) (err error) {
 	op := c.newOperation(base.OpUploadPlugin, input.PluginName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	req.EnableContentMD5d()
 	req.SetBufferBody(input.Buffer.Bytes())
 	req.SetHeader(base.HTTPHeaderContentType, base.ContentTypeJar)
 	return req.Send()
 }
 
-func (c *Pipeline) UploadPluginFromFile(input *UploadPluginFromFileInput) (err error) {
+func (c *Pipeline) UploadPluginFromFile(input *UploadPluginFromFileInput) error {
+	return c.UploadPluginFromFileWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) UploadPluginFromFileWithContext(ctx context.Context, input *UploadPluginFromFileInput) (err error) {
 	op := c.newOperation(base.OpUploadPlugin, input.PluginName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	req.EnableContentMD5d()
 
 	file, err := os.Open(input.FilePath)
@@ -206,33 +295,49 @@ func (c *Pipeline) UploadPluginFromFile(input *UploadPluginFromFileInput) (err e
 	return req.Send()
 }
 
-func (c *Pipeline) ListPlugins(input *ListPluginsInput) (output *ListPluginsOutput, err error) {
+func (c *Pipeline) ListPlugins(input *ListPluginsInput) (*ListPluginsOutput, error) {
+	return c.ListPluginsWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) ListPluginsWithContext(ctx context.Context, input *ListPluginsInput) (output *ListPluginsOutput, err error) {
 	op := c.newOperation(base.OpListPlugins)
 
 	output = &ListPluginsOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) GetPlugin(input *GetPluginInput) (output *GetPluginOutput, err error) {
+func (c *Pipeline) GetPlugin(input *GetPluginInput) (*GetPluginOutput, error) {
+	return c.GetPluginWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) GetPluginWithContext(ctx context.Context, input *GetPluginInput) (output *GetPluginOutput, err error) {
 	op := c.newOperation(base.OpGetPlugin, input.PluginName)
 
 	output = &GetPluginOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) DeletePlugin(input *DeletePluginInput) (err error) {
+func (c *Pipeline) DeletePlugin(input *DeletePluginInput) error {
+	return c.DeletePluginWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) DeletePluginWithContext(ctx context.Context, input *DeletePluginInput) (err error) {
 	op := c.newOperation(base.OpDeletePlugin, input.PluginName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) CreateTransform(input *CreateTransformInput) (err error) {
+func (c *Pipeline) CreateTransform(input *CreateTransformInput) error {
+	return c.CreateTransformWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) CreateTransformWithContext(ctx context.Context, input *CreateTransformInput) (err error) {
 	op := c.newOperation(base.OpCreateTransform, input.SrcRepoName, input.TransformName, input.DestRepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input.Spec); err != nil {
 		return
 	}
@@ -240,10 +345,14 @@ func (c *Pipeline) CreateTransform(input *CreateTransformInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) UpdateTransform(input *UpdateTransformInput) (err error) {
+func (c *Pipeline) UpdateTransform(input *UpdateTransformInput) error {
+	return c.UpdateTransformWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) UpdateTransformWithContext(ctx context.Context, input *UpdateTransformInput) (err error) {
 	op := c.newOperation(base.OpUpdateTransform, input.SrcRepoName, input.TransformName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input.Spec); err != nil {
 		return
 	}
@@ -251,33 +360,65 @@ func (c *Pipeline) UpdateTransform(input *UpdateTransformInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) ListTransforms(input *ListTransformsInput) (output *ListTransformsOutput, err error) {
+func (c *Pipeline) ListTransforms(input *ListTransformsInput) (*ListTransformsOutput, error) {
+	return c.ListTransformsWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) ListTransformsWithContext(ctx context.Context, input *ListTransformsInput) (output *ListTransformsOutput, err error) {
 	op := c.newOperation(base.OpListTransforms, input.RepoName)
 
 	output = &ListTransformsOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) GetTransform(input *GetTransformInput) (output *GetTransformOutput, err error) {
+func (c *Pipeline) GetTransform(input *GetTransformInput) (*GetTransformOutput, error) {
+	return c.GetTransformWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) GetTransformWithContext(ctx context.Context, input *GetTransformInput) (output *GetTransformOutput, err error) {
 	op := c.newOperation(base.OpGetTransform, input.RepoName, input.TransformName)
 
 	output = &GetTransformOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) DeleteTransform(input *DeleteTransformInput) (err error) {
+func (c *Pipeline) DeleteTransform(input *DeleteTransformInput) error {
+	return c.DeleteTransformWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) DeleteTransformWithContext(ctx context.Context, input *DeleteTransformInput) (err error) {
 	op := c.newOperation(base.OpDeleteTransform, input.RepoName, input.TransformName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) CreateExport(input *CreateExportInput) (err error) {
+func (c *Pipeline) VerifyTransform(input *VerifyTransformInput) (*VerifyTransformOutput, error) {
+	return c.VerifyTransformWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) VerifyTransformWithContext(ctx context.Context, input *VerifyTransformInput) (output *VerifyTransformOutput, err error) {
+	op := c.newOperation(base.OpVerifyTransform)
+
+	output = &VerifyTransformOutput{}
+	req := c.newRequest(ctx, op, input.Token, &output)
+	if err = req.SetVariantBody(input); err != nil {
+		return
+	}
+	req.SetHeader(base.HTTPHeaderContentType, base.ContentTypeJson)
+	return output, req.Send()
+}
+
+func (c *Pipeline) CreateExport(input *CreateExportInput) error {
+	return c.CreateExportWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) CreateExportWithContext(ctx context.Context, input *CreateExportInput) (err error) {
 	op := c.newOperation(base.OpCreateExport, input.RepoName, input.ExportName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -285,10 +426,14 @@ func (c *Pipeline) CreateExport(input *CreateExportInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) UpdateExport(input *UpdateExportInput) (err error) {
+func (c *Pipeline) UpdateExport(input *UpdateExportInput) error {
+	return c.UpdateExportWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) UpdateExportWithContext(ctx context.Context, input *UpdateExportInput) (err error) {
 	op := c.newOperation(base.OpUpdateExport, input.RepoName, input.ExportName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -296,33 +441,64 @@ func (c *Pipeline) UpdateExport(input *UpdateExportInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) ListExports(input *ListExportsInput) (output *ListExportsOutput, err error) {
+func (c *Pipeline) ListExports(input *ListExportsInput) (*ListExportsOutput, error) {
+	return c.ListExportsWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) ListExportsWithContext(ctx context.Context, input *ListExportsInput) (output *ListExportsOutput, err error) {
 	op := c.newOperation(base.OpListExports, input.RepoName)
 
 	output = &ListExportsOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) GetExport(input *GetExportInput) (output *GetExportOutput, err error) {
+func (c *Pipeline) GetExport(input *GetExportInput) (*GetExportOutput, error) {
+	return c.GetExportWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) GetExportWithContext(ctx context.Context, input *GetExportInput) (output *GetExportOutput, err error) {
 	op := c.newOperation(base.OpGetExport, input.RepoName, input.ExportName)
 
 	output = &GetExportOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) DeleteExport(input *DeleteExportInput) (err error) {
+func (c *Pipeline) DeleteExport(input *DeleteExportInput) error {
+	return c.DeleteExportWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) DeleteExportWithContext(ctx context.Context, input *DeleteExportInput) (err error) {
 	op := c.newOperation(base.OpDeleteExport, input.RepoName, input.ExportName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) CreateDatasource(input *CreateDatasourceInput) (err error) {
+func (c *Pipeline) VerifyExport(input *VerifyExportInput) error {
+	return c.VerifyExportWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) VerifyExportWithContext(ctx context.Context, input *VerifyExportInput) (err error) {
+	op := c.newOperation(base.OpVerifyExport)
+
+	req := c.newRequest(ctx, op, input.Token, nil)
+	if err = req.SetVariantBody(input); err != nil {
+		return
+	}
+	req.SetHeader(base.HTTPHeaderContentType, base.ContentTypeJson)
+	return req.Send()
+}
+
+func (c *Pipeline) CreateDatasource(input *CreateDatasourceInput) error {
+	return c.CreateDatasourceWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) CreateDatasourceWithContext(ctx context.Context, input *CreateDatasourceInput) (err error) {
 	op := c.newOperation(base.OpCreateDatasource, input.DatasourceName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -330,33 +506,49 @@ func (c *Pipeline) CreateDatasource(input *CreateDatasourceInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) ListDatasources() (output *ListDatasourcesOutput, err error) {
+func (c *Pipeline) ListDatasources() (*ListDatasourcesOutput, error) {
+	return c.ListDatasourcesWithContext(context.Background())
+}
+
+func (c *Pipeline) ListDatasourcesWithContext(ctx context.Context) (output *ListDatasourcesOutput, err error) {
 	op := c.newOperation(base.OpListDatasources)
 
 	output = &ListDatasourcesOutput{}
-	req := c.newRequest(op, "", &output)
+	req := c.newRequest(ctx, op, "", &output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) GetDatasource(input *GetDatasourceInput) (output *GetDatasourceOutput, err error) {
+func (c *Pipeline) GetDatasource(input *GetDatasourceInput) (*GetDatasourceOutput, error) {
+	return c.GetDatasourceWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) GetDatasourceWithContext(ctx context.Context, input *GetDatasourceInput) (output *GetDatasourceOutput, err error) {
 	op := c.newOperation(base.OpGetDatasource, input.DatasourceName)
 
 	output = &GetDatasourceOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) DeleteDatasource(input *DeleteDatasourceInput) (err error) {
+func (c *Pipeline) DeleteDatasource(input *DeleteDatasourceInput) error {
+	return c.DeleteDatasourceWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) DeleteDatasourceWithContext(ctx context.Context, input *DeleteDatasourceInput) (err error) {
 	op := c.newOperation(base.OpDeleteDatasource, input.DatasourceName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) CreateJob(input *CreateJobInput) (err error) {
+func (c *Pipeline) CreateJob(input *CreateJobInput) error {
+	return c.CreateJobWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) CreateJobWithContext(ctx context.Context, input *CreateJobInput) (err error) {
 	op := c.newOperation(base.OpCreateJob, input.JobName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -364,7 +556,11 @@ func (c *Pipeline) CreateJob(input *CreateJobInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) ListJobs(input *ListJobsInput) (output *ListJobsOutput, err error) {
+func (c *Pipeline) ListJobs(input *ListJobsInput) (*ListJobsOutput, error) {
+	return c.ListJobsWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) ListJobsWithContext(ctx context.Context, input *ListJobsInput) (output *ListJobsOutput, err error) {
 	query := ""
 	values := url.Values{}
 	if input.SrcJobName != "" {
@@ -379,29 +575,41 @@ func (c *Pipeline) ListJobs(input *ListJobsInput) (output *ListJobsOutput, err e
 	op := c.newOperation(base.OpListJobs, query)
 
 	output = &ListJobsOutput{}
-	req := c.newRequest(op, "", &output)
+	req := c.newRequest(ctx, op, "", &output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) GetJob(input *GetJobInput) (output *GetJobOutput, err error) {
+func (c *Pipeline) GetJob(input *GetJobInput) (*GetJobOutput, error) {
+	return c.GetJobWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) GetJobWithContext(ctx context.Context, input *GetJobInput) (output *GetJobOutput, err error) {
 	op := c.newOperation(base.OpGetJob, input.JobName)
 
 	output = &GetJobOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) DeleteJob(input *DeleteJobInput) (err error) {
+func (c *Pipeline) DeleteJob(input *DeleteJobInput) error {
+	return c.DeleteJobWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) DeleteJobWithContext(ctx context.Context, input *DeleteJobInput) (err error) {
 	op := c.newOperation(base.OpDeleteJob, input.JobName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) StartJob(input *StartJobInput) (err error) {
+func (c *Pipeline) StartJob(input *StartJobInput) error {
+	return c.StartJobWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) StartJobWithContext(ctx context.Context, input *StartJobInput) (err error) {
 	op := c.newOperation(base.OpStartJob, input.JobName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -409,25 +617,37 @@ func (c *Pipeline) StartJob(input *StartJobInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) GetJobHistory(input *GetJobHistoryInput) (output *GetJobHistoryOutput, err error) {
+func (c *Pipeline) GetJobHistory(input *GetJobHistoryInput) (*GetJobHistoryOutput, error) {
+	return c.GetJobHistoryWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) GetJobHistoryWithContext(ctx context.Context, input *GetJobHistoryInput) (output *GetJobHistoryOutput, err error) {
 	op := c.newOperation(base.OpGetJobHistory, input.JobName)
 
 	output = &GetJobHistoryOutput{}
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) StopJob(input *StopJobInput) (err error) {
+func (c *Pipeline) StopJob(input *StopJobInput) error {
+	return c.StopJobWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) StopJobWithContext(ctx context.Context, input *StopJobInput) (err error) {
 	op := c.newOperation(base.OpStopJob, input.JobName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) CreateJobExport(input *CreateJobExportInput) (err error) {
+func (c *Pipeline) CreateJobExport(input *CreateJobExportInput) error {
+	return c.CreateJobExportWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) CreateJobExportWithContext(ctx context.Context, input *CreateJobExportInput) (err error) {
 	op := c.newOperation(base.OpCreateJobExport, input.JobName, input.ExportName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -435,34 +655,50 @@ func (c *Pipeline) CreateJobExport(input *CreateJobExportInput) (err error) {
 	return req.Send()
 }
 
-func (c *Pipeline) ListJobExports(input *ListJobExportsInput) (output *ListJobExportsOutput, err error) {
+func (c *Pipeline) ListJobExports(input *ListJobExportsInput) (*ListJobExportsOutput, error) {
+	return c.ListJobExportsWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) ListJobExportsWithContext(ctx context.Context, input *ListJobExportsInput) (output *ListJobExportsOutput, err error) {
 	op := c.newOperation(base.OpListJobExports, input.JobName)
 
 	output = &ListJobExportsOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) GetJobExport(input *GetJobExportInput) (output *GetJobExportOutput, err error) {
+func (c *Pipeline) GetJobExport(input *GetJobExportInput) (*GetJobExportOutput, error) {
+	return c.GetJobExportWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) GetJobExportWithContext(ctx context.Context, input *GetJobExportInput) (output *GetJobExportOutput, err error) {
 	op := c.newOperation(base.OpGetJobExport, input.JobName, input.ExportName)
 
 	output = &GetJobExportOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Pipeline) DeleteJobExport(input *DeleteJobExportInput) (err error) {
+func (c *Pipeline) DeleteJobExport(input *DeleteJobExportInput) error {
+	return c.DeleteJobExportWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) DeleteJobExportWithContext(ctx context.Context, input *DeleteJobExportInput) (err error) {
 	op := c.newOperation(base.OpDeleteJobExport, input.JobName, input.ExportName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Pipeline) RetrieveSchema(input *RetrieveSchemaInput) (output *RetrieveSchemaOutput, err error) {
+func (c *Pipeline) RetrieveSchema(input *RetrieveSchemaInput) (*RetrieveSchemaOutput, error) {
+	return c.RetrieveSchemaWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) RetrieveSchemaWithContext(ctx context.Context, input *RetrieveSchemaInput) (output *RetrieveSchemaOutput, err error) {
 	op := c.newOperation(base.OpRetrieveSchema)
 
 	output = &RetrieveSchemaOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -471,5 +707,9 @@ func (c *Pipeline) RetrieveSchema(input *RetrieveSchemaInput) (output *RetrieveS
 }
 
 func (c *Pipeline) MakeToken(desc *base.TokenDesc) (string, error) {
-	return base.MakeTokenInternal(c.Config.Ak, c.Config.Sk, desc)
+	creds, err := c.Config.RetrieveCredentials()
+	if err != nil {
+		return "", err
+	}
+	return base.MakeTokenWithClock(creds.AccessKey, creds.SecretKey, desc, c.Config.Clock)
 }
@@ -0,0 +1,313 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+const (
+	defaultBatchPoints int           = 1000
+	defaultBatchBytes  int           = 2 * 1024 * 1024
+	defaultLinger      time.Duration = time.Second
+	defaultQueueSize   int           = 1000
+	defaultConcurrency int           = 1
+)
+
+var ErrBatchWriterClosed = errors.New("batch writer has been closed")
+
+// BatchWriterConfig 配置BatchWriter的攒批策略，零值字段使用默认值
+type BatchWriterConfig struct {
+	MaxBatchPoints int           // 单个批次最多包含的点数
+	MaxBatchBytes  int           // 单个批次最大的字节数
+	Linger         time.Duration // 批次从第一个点写入开始最长等待多久就发送
+	QueueSize      int           // 待处理点队列的长度，队列满了之后Write会阻塞
+	Concurrency    int           // 同时发送批次的goroutine数量
+	// OnError 在批次发送失败时被调用。OnError在发送批次的goroutine之外执行，可以调用Write重新写入失败的点，
+	// 但是不能调用Flush和Close；多个批次的OnError可能被同时调用
+	OnError func(*BatchError)
+}
+
+func (c *BatchWriterConfig) Validate() (err error) {
+	if c.MaxBatchPoints < 0 {
+		return reqerr.NewInvalidArgs("MaxBatchPoints", "max batch points should not be negative")
+	}
+	if c.MaxBatchBytes < 0 {
+		return reqerr.NewInvalidArgs("MaxBatchBytes", "max batch bytes should not be negative")
+	}
+	if c.Linger < 0 {
+		return reqerr.NewInvalidArgs("Linger", "linger should not be negative")
+	}
+	if c.QueueSize < 0 {
+		return reqerr.NewInvalidArgs("QueueSize", "queue size should not be negative")
+	}
+	if c.Concurrency < 0 {
+		return reqerr.NewInvalidArgs("Concurrency", "concurrency should not be negative")
+	}
+	return
+}
+
+// BatchError 表示一个批次发送失败，Points是这个批次中未能写入的点
+type BatchError struct {
+	RepoName string
+	Points   Points
+	Err      error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("post %d points to repo %s failed: %v", len(e.Points), e.RepoName, e.Err)
+}
+
+type batchEntry struct {
+	repoName string
+	point    Point
+	size     int
+}
+
+type batch struct {
+	repoName string
+	points   Points
+	size     int
+	created  time.Time
+	flush    *sync.WaitGroup // 批次发送完成并且OnError返回之后Done
+}
+
+// BatchWriter 将多个goroutine写入的点按repo攒批之后调用PostData发送，
+// 可以并发调用Write，使用完毕之后必须调用Close以发送剩余的数据。
+type BatchWriter struct {
+	client  *Pipeline
+	config  BatchWriterConfig
+	input   chan batchEntry
+	flushCh chan chan struct{}
+	sendCh  chan *batch
+	batches map[string]*batch
+	flush   *sync.WaitGroup // 上一次Flush之后派发的批次，只在run中修改
+	workers sync.WaitGroup
+	reports sync.WaitGroup
+	lock    sync.RWMutex
+	closed  bool
+}
+
+func (c *Pipeline) NewBatchWriter(cfg *BatchWriterConfig) (w *BatchWriter, err error) {
+	if cfg == nil {
+		cfg = &BatchWriterConfig{}
+	}
+	if err = cfg.Validate(); err != nil {
+		return
+	}
+	config := *cfg
+	if config.MaxBatchPoints == 0 {
+		config.MaxBatchPoints = defaultBatchPoints
+	}
+	if config.MaxBatchBytes == 0 {
+		config.MaxBatchBytes = defaultBatchBytes
+	}
+	if config.Linger == 0 {
+		config.Linger = defaultLinger
+	}
+	if config.QueueSize == 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.Concurrency == 0 {
+		config.Concurrency = defaultConcurrency
+	}
+
+	w = &BatchWriter{
+		client:  c,
+		config:  config,
+		input:   make(chan batchEntry, config.QueueSize),
+		flushCh: make(chan chan struct{}),
+		sendCh:  make(chan *batch, config.Concurrency),
+		batches: make(map[string]*batch),
+		flush:   &sync.WaitGroup{},
+	}
+	w.workers.Add(1 + config.Concurrency)
+	go w.run()
+	for i := 0; i < config.Concurrency; i++ {
+		go w.sendLoop()
+	}
+	return
+}
+
+// Write 将一个点加入到repoName对应的批次中，点会在批次满足发送条件之后被异步发送
+func (w *BatchWriter) Write(repoName string, point Point) error {
+	if err := validateRepoName(repoName); err != nil {
+		return err
+	}
+	entry := batchEntry{
+		repoName: repoName,
+		point:    point,
+		size:     len(Points{point}.Buffer()) + 1,
+	}
+
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.closed {
+		return ErrBatchWriterClosed
+	}
+	w.input <- entry
+	return nil
+}
+
+// Flush 立即发送所有未满的批次，并等待调用Flush之前提交的批次发送完成，之后写入的点不会被等待
+func (w *BatchWriter) Flush() error {
+	w.lock.RLock()
+	if w.closed {
+		w.lock.RUnlock()
+		return ErrBatchWriterClosed
+	}
+	done := make(chan struct{})
+	w.flushCh <- done
+	w.lock.RUnlock()
+	<-done
+	return nil
+}
+
+// Close 停止接收新的点，并在发送完所有剩余的批次之后返回
+func (w *BatchWriter) Close() error {
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return ErrBatchWriterClosed
+	}
+	w.closed = true
+	close(w.input)
+	w.lock.Unlock()
+
+	w.workers.Wait()
+	w.reports.Wait()
+	return nil
+}
+
+func (w *BatchWriter) run() {
+	defer w.workers.Done()
+
+	interval := w.config.Linger / 2
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry, ok := <-w.input:
+			if !ok {
+				w.dispatchAll()
+				close(w.sendCh)
+				return
+			}
+			w.add(entry)
+		case now := <-ticker.C:
+			for repoName, b := range w.batches {
+				if now.Sub(b.created) >= w.config.Linger {
+					w.dispatch(repoName)
+				}
+			}
+		case done := <-w.flushCh:
+			w.drain()
+			w.dispatchAll()
+			// 只等待这次Flush之前派发的批次，之后派发的批次计入新的WaitGroup
+			flush := w.flush
+			w.flush = &sync.WaitGroup{}
+			go func() {
+				flush.Wait()
+				close(done)
+			}()
+		}
+	}
+}
+
+// drain 处理完调用Flush之前已经写入队列的点，持续写入时不会等待之后写入的点
+func (w *BatchWriter) drain() {
+	for n := len(w.input); n > 0; n-- {
+		select {
+		case entry, ok := <-w.input:
+			if !ok {
+				return
+			}
+			w.add(entry)
+		default:
+			return
+		}
+	}
+}
+
+func (w *BatchWriter) add(entry batchEntry) {
+	b, ok := w.batches[entry.repoName]
+	if ok && b.size+entry.size > w.config.MaxBatchBytes {
+		w.dispatch(entry.repoName)
+		ok = false
+	}
+	if !ok {
+		b = &batch{repoName: entry.repoName, created: time.Now()}
+		w.batches[entry.repoName] = b
+	}
+	b.points = append(b.points, entry.point)
+	b.size += entry.size
+	if len(b.points) >= w.config.MaxBatchPoints || b.size >= w.config.MaxBatchBytes {
+		w.dispatch(entry.repoName)
+	}
+}
+
+func (w *BatchWriter) dispatch(repoName string) {
+	b := w.batches[repoName]
+	delete(w.batches, repoName)
+	b.flush = w.flush
+	b.flush.Add(1)
+	w.sendCh <- b
+}
+
+func (w *BatchWriter) dispatchAll() {
+	for repoName := range w.batches {
+		w.dispatch(repoName)
+	}
+}
+
+func (w *BatchWriter) sendLoop() {
+	defer w.workers.Done()
+	for b := range w.sendCh {
+		errs := w.post(b.repoName, b.points, nil)
+		if len(errs) == 0 || w.config.OnError == nil {
+			for _, e := range errs {
+				if w.client.Config.Logger != nil {
+					w.client.Config.Logger.Errorf("batch writer post %d points to repo %s failed, err: %v", len(e.Points), e.RepoName, e.Err)
+				}
+			}
+			b.flush.Done()
+			continue
+		}
+		// OnError可能调用Write，在发送goroutine中调用会因为队列已满而死锁
+		w.reports.Add(1)
+		go w.report(b, errs)
+	}
+}
+
+func (w *BatchWriter) report(b *batch, errs []*BatchError) {
+	defer w.reports.Done()
+	defer b.flush.Done()
+	for _, e := range errs {
+		w.config.OnError(e)
+	}
+}
+
+// post 发送一个批次，服务端认为请求体过大(E18005)时将批次一分为二后分别发送，返回所有发送失败的部分
+func (w *BatchWriter) post(repoName string, points Points, errs []*BatchError) []*BatchError {
+	err := w.client.PostDataWithContext(context.Background(), &PostDataInput{
+		RepoName: repoName,
+		Points:   points,
+	})
+	if err == nil {
+		return errs
+	}
+	if v, ok := err.(*reqerr.RequestError); ok && v.ErrorType == reqerr.EntityTooLargeError && len(points) > 1 {
+		mid := len(points) / 2
+		errs = w.post(repoName, points[:mid], errs)
+		return w.post(repoName, points[mid:], errs)
+	}
+	return append(errs, &BatchError{RepoName: repoName, Points: points, Err: err})
+}
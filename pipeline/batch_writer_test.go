@@ -0,0 +1,252 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+)
+
+type postRecorder struct {
+	sync.Mutex
+	maxBody int
+	posts   map[string][]int
+}
+
+func (p *postRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	if p.maxBody > 0 && len(body) > p.maxBody {
+		w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte(`{"error":"E18005: entity too large"}`))
+		return
+	}
+	repo := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/repos/"), "/data")
+	p.Lock()
+	p.posts[repo] = append(p.posts[repo], len(strings.Split(string(body), "\n")))
+	p.Unlock()
+}
+
+func (p *postRecorder) total(repo string) (n int) {
+	p.Lock()
+	defer p.Unlock()
+	for _, c := range p.posts[repo] {
+		n += c
+	}
+	return
+}
+
+func newTestPipeline(t *testing.T, h http.Handler) (*Pipeline, *httptest.Server) {
+	ts := httptest.NewServer(h)
+	cfg := NewConfig().
+		WithEndpoint(ts.URL).
+		WithAccessKeySecretKey("ak", "sk").
+		WithLogger(&base.DefaultLogger{Logger: log.New(ioutil.Discard, "", 0)})
+	c, err := newClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, ts
+}
+
+func testPoint(i int) Point {
+	return Point{Fields: []PointField{{Key: "f1", Value: fmt.Sprintf("value_%d", i)}}}
+}
+
+func TestBatchWriter(t *testing.T) {
+	rec := &postRecorder{posts: map[string][]int{}}
+	c, ts := newTestPipeline(t, rec)
+	defer ts.Close()
+
+	w, err := c.NewBatchWriter(&BatchWriterConfig{MaxBatchPoints: 10, Linger: time.Hour, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				if err := w.Write(fmt.Sprintf("repo_%d", g%2), testPoint(i)); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, repo := range []string{"repo_0", "repo_1"} {
+		if n := rec.total(repo); n != 50 {
+			t.Errorf("repo %s should receive 50 points, got %d", repo, n)
+		}
+		for _, c := range rec.posts[repo] {
+			if c > 10 {
+				t.Errorf("batch size %d exceeds max batch points", c)
+			}
+		}
+	}
+	if err = w.Write("repo_0", testPoint(0)); err != ErrBatchWriterClosed {
+		t.Errorf("write after close should fail, got %v", err)
+	}
+}
+
+func TestBatchWriterLinger(t *testing.T) {
+	rec := &postRecorder{posts: map[string][]int{}}
+	c, ts := newTestPipeline(t, rec)
+	defer ts.Close()
+
+	w, err := c.NewBatchWriter(&BatchWriterConfig{Linger: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write("repo", testPoint(1))
+	time.Sleep(200 * time.Millisecond)
+	if n := rec.total("repo"); n != 1 {
+		t.Errorf("point should be sent after linger, got %d", n)
+	}
+}
+
+func TestBatchWriterSplitEntityTooLarge(t *testing.T) {
+	rec := &postRecorder{posts: map[string][]int{}, maxBody: 100}
+	c, ts := newTestPipeline(t, rec)
+	defer ts.Close()
+
+	var errs []*BatchError
+	w, err := c.NewBatchWriter(&BatchWriterConfig{
+		Linger:  time.Hour,
+		OnError: func(e *BatchError) { errs = append(errs, e) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		w.Write("repo", testPoint(i))
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := rec.total("repo"); n != 20 {
+		t.Errorf("all points should be sent after splitting, got %d", n)
+	}
+	if len(errs) != 0 {
+		t.Errorf("no batch should fail, got %v", errs)
+	}
+	w.Close()
+}
+
+// failingServer 拒绝前fail个请求，之后记录收到的点数
+type failingServer struct {
+	postRecorder
+	fail int
+}
+
+func (s *failingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	fail := s.fail > 0
+	s.fail--
+	s.Unlock()
+	if fail {
+		w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"E18103: invalid data"}`))
+		return
+	}
+	s.postRecorder.ServeHTTP(w, r)
+}
+
+func TestBatchWriterRequeueOnError(t *testing.T) {
+	s := &failingServer{postRecorder: postRecorder{posts: map[string][]int{}}, fail: 10}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+
+	var w *BatchWriter
+	w, err := c.NewBatchWriter(&BatchWriterConfig{
+		MaxBatchPoints: 1,
+		Linger:         time.Hour,
+		QueueSize:      1,
+		OnError: func(e *BatchError) {
+			for _, p := range e.Points {
+				if err := w.Write(e.RepoName, p); err != nil {
+					t.Error(err)
+				}
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			w.Write("repo", testPoint(i))
+		}
+		w.Flush()
+		// 等待重新写入的点也被发送
+		for s.total("repo") < 20 {
+			w.Flush()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("batch writer deadlocked, received %d points", s.total("repo"))
+	}
+	w.Close()
+	if n := s.total("repo"); n != 20 {
+		t.Errorf("expect all points to be sent after requeue, got %d", n)
+	}
+}
+
+func TestBatchWriterFlushUnderLoad(t *testing.T) {
+	rec := &postRecorder{posts: map[string][]int{}}
+	c, ts := newTestPipeline(t, rec)
+	defer ts.Close()
+
+	w, err := c.NewBatchWriter(&BatchWriterConfig{MaxBatchPoints: 5, Linger: time.Hour, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				w.Write("repo", testPoint(i))
+			}
+		}
+	}()
+	// 持续写入时Flush只等待调用之前提交的批次
+	for i := 0; i < 10; i++ {
+		flushed := make(chan struct{})
+		go func() {
+			w.Flush()
+			close(flushed)
+		}()
+		select {
+		case <-flushed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("flush should return under steady writes")
+		}
+	}
+	close(stop)
+	wg.Wait()
+	w.Close()
+}
@@ -0,0 +1,397 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+// PointEncoder返回的字段错误，可以通过errors.Is判断，map中嵌套的字段也会返回这些错误
+var (
+	ErrUnknownField         = errors.New("unknown field")
+	ErrDuplicatedField      = errors.New("duplicated field")
+	ErrRequiredFieldMissing = errors.New("required field is missing")
+)
+
+// PointError 表示一个点中不符合schema的字段
+type PointError struct {
+	Index int // 点在Points中的下标
+	Key   string
+	Err   error
+}
+
+func (e *PointError) Error() string {
+	return fmt.Sprintf("point %d field %s: %v", e.Index, e.Key, e.Err)
+}
+
+func (e *PointError) Unwrap() error {
+	return e.Err
+}
+
+// PointsError 包含所有不符合schema的点的错误，按点的下标排列
+type PointsError struct {
+	Errors []*PointError
+}
+
+// maxPointErrorsInMessage 是PointsError.Error()中最多列出的错误数
+const maxPointErrorsInMessage = 3
+
+func (e *PointsError) Error() string {
+	var msgs []string
+	for i, pe := range e.Errors {
+		if i == maxPointErrorsInMessage {
+			msgs = append(msgs, fmt.Sprintf("and %d more", len(e.Errors)-i))
+			break
+		}
+		msgs = append(msgs, pe.Error())
+	}
+	return fmt.Sprintf("%d invalid fields in points: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Indexes 返回包含错误的点的下标，按升序排列且不重复
+func (e *PointsError) Indexes() []int {
+	var indexes []int
+	for _, pe := range e.Errors {
+		if n := len(indexes); n == 0 || indexes[n-1] != pe.Index {
+			indexes = append(indexes, pe.Index)
+		}
+	}
+	return indexes
+}
+
+// Is 使errors.Is(err, reqerr.ErrInvalidArgs)成立
+func (e *PointsError) Is(target error) bool {
+	return target == reqerr.ErrInvalidArgs
+}
+
+// PointEncoder 按repo的schema校验并编码Points：date编码为RFC3339格式，long编码为整数，
+// float不使用科学计数法，array和map编码为JSON，map中的字段按嵌套的schema编码。
+// PointEncoder创建之后是只读的，可以被多个goroutine同时使用
+type PointEncoder struct {
+	fields *encoderFields
+}
+
+type encoderFields struct {
+	entries  map[string]*encoderEntry
+	required []string
+}
+
+type encoderEntry struct {
+	RepoSchemaEntry
+	nested *encoderFields // map类型并且指定了Schema时不为nil
+}
+
+func NewPointEncoder(schema []RepoSchemaEntry) (*PointEncoder, error) {
+	fields, err := newEncoderFields(schema)
+	if err != nil {
+		return nil, err
+	}
+	return &PointEncoder{fields: fields}, nil
+}
+
+func newEncoderFields(schema []RepoSchemaEntry) (*encoderFields, error) {
+	fields := &encoderFields{entries: make(map[string]*encoderEntry, len(schema))}
+	for _, e := range schema {
+		if err := e.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := fields.entries[e.Key]; ok {
+			return nil, reqerr.NewInvalidArgs("Schema", fmt.Sprintf("duplicated field key: %s", e.Key))
+		}
+		entry := &encoderEntry{RepoSchemaEntry: e}
+		if e.ValueType == "map" && len(e.Schema) > 0 {
+			nested, err := newEncoderFields(e.Schema)
+			if err != nil {
+				return nil, err
+			}
+			entry.nested = nested
+		}
+		fields.entries[e.Key] = entry
+		if e.Required {
+			fields.required = append(fields.required, e.Key)
+		}
+	}
+	return fields, nil
+}
+
+// Encode 编码所有的点，返回值可以直接作为PostData的请求体。
+// 有点不符合schema时返回*PointsError，其中包含每个错误字段，此时不返回任何数据
+func (e *PointEncoder) Encode(points Points) ([]byte, error) {
+	var buf bytes.Buffer
+	var errs []*PointError
+	for i, p := range points {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		errs = append(errs, e.encodePoint(&buf, i, p)...)
+	}
+	if len(errs) > 0 {
+		return nil, &PointsError{Errors: errs}
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodePoint 编码一个点，返回的错误为*PointsError
+func (e *PointEncoder) EncodePoint(p Point) ([]byte, error) {
+	var buf bytes.Buffer
+	if errs := e.encodePoint(&buf, 0, p); len(errs) > 0 {
+		return nil, &PointsError{Errors: errs}
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *PointEncoder) encodePoint(buf *bytes.Buffer, index int, p Point) (errs []*PointError) {
+	seen := make(map[string]bool, len(p.Fields))
+	first := true
+	for _, f := range p.Fields {
+		entry, ok := e.fields.entries[f.Key]
+		if !ok {
+			errs = append(errs, &PointError{Index: index, Key: f.Key, Err: ErrUnknownField})
+			continue
+		}
+		if seen[f.Key] {
+			errs = append(errs, &PointError{Index: index, Key: f.Key, Err: ErrDuplicatedField})
+			continue
+		}
+		seen[f.Key] = true
+		if f.Value == nil {
+			continue
+		}
+		v, err := entry.convert(f.Value)
+		if err != nil {
+			errs = append(errs, &PointError{Index: index, Key: f.Key, Err: err})
+			continue
+		}
+		text, err := encoderText(v)
+		if err != nil {
+			errs = append(errs, &PointError{Index: index, Key: f.Key, Err: err})
+			continue
+		}
+		if !first {
+			buf.WriteByte('\t')
+		}
+		first = false
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		buf.WriteString(escapeStringField(text))
+	}
+	for _, key := range e.fields.required {
+		if !seen[key] || pointValue(p, key) == nil {
+			errs = append(errs, &PointError{Index: index, Key: key, Err: ErrRequiredFieldMissing})
+		}
+	}
+	return
+}
+
+func pointValue(p Point, key string) interface{} {
+	for _, f := range p.Fields {
+		if f.Key == key {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// encoderText 返回convert的结果在PostData请求体中的文本形式
+func encoderText(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return string(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// convert 把v转换为schema类型对应的值：string和date为string，long和float为json.Number，
+// boolean为bool，array为[]interface{}，map为map[string]interface{}
+func (e *encoderEntry) convert(v interface{}) (interface{}, error) {
+	switch e.ValueType {
+	case "string":
+		return convertString(v)
+	case "long":
+		return convertLong(v)
+	case "float":
+		return convertFloat(v)
+	case "boolean":
+		return convertBool(v)
+	case "date":
+		return convertDate(v)
+	case "array":
+		return e.convertArray(v)
+	case "map":
+		return e.convertMap(v)
+	}
+	return nil, fmt.Errorf("unsupported field type %s", e.ValueType)
+}
+
+func convertString(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	case json.Number:
+		return string(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	if n, err := convertFloat(v); err == nil {
+		return string(n.(json.Number)), nil
+	}
+	return nil, fmt.Errorf("cannot use %T as string", v)
+}
+
+func convertLong(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows long", rv.Uint())
+		}
+		return json.Number(strconv.FormatUint(rv.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return nil, fmt.Errorf("%v is not a valid long", f)
+		}
+		return json.Number(strconv.FormatInt(int64(f), 10)), nil
+	case reflect.String:
+		if _, err := strconv.ParseInt(rv.String(), 10, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a valid long", rv.String())
+		}
+		return json.Number(rv.String()), nil
+	}
+	return nil, fmt.Errorf("cannot use %T as long", v)
+}
+
+func convertFloat(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return json.Number(strconv.FormatUint(rv.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%v is not a valid float", f)
+		}
+		bitSize := 64
+		if rv.Kind() == reflect.Float32 {
+			bitSize = 32
+		}
+		return json.Number(strconv.FormatFloat(f, 'f', -1, bitSize)), nil
+	case reflect.String:
+		f, err := strconv.ParseFloat(rv.String(), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%q is not a valid float", rv.String())
+		}
+		return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), nil
+	}
+	return nil, fmt.Errorf("cannot use %T as float", v)
+}
+
+func convertBool(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid boolean", v)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot use %T as boolean", v)
+}
+
+func convertDate(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case *time.Time:
+		if v != nil {
+			return v.Format(time.RFC3339Nano), nil
+		}
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			return nil, fmt.Errorf("%q is not a RFC3339 date", v)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("cannot use %T as date", v)
+}
+
+func (e *encoderEntry) convertArray(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, fmt.Errorf("cannot use %T as array", v)
+	}
+	elem := &encoderEntry{RepoSchemaEntry: RepoSchemaEntry{ValueType: e.ElemType}}
+	values := make([]interface{}, rv.Len())
+	for i := range values {
+		ev, err := elem.convert(rv.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		values[i] = ev
+	}
+	return values, nil
+}
+
+func (e *encoderEntry) convertMap(v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("cannot use %T as map", v)
+	}
+	values := make(map[string]interface{}, rv.Len())
+	for _, k := range rv.MapKeys() {
+		values[k.String()] = rv.MapIndex(k).Interface()
+	}
+	if e.nested == nil {
+		return values, nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		entry, ok := e.nested.entries[k]
+		if !ok {
+			return nil, fmt.Errorf("nested field %s: %w", k, ErrUnknownField)
+		}
+		if values[k] == nil {
+			continue
+		}
+		nv, err := entry.convert(values[k])
+		if err != nil {
+			return nil, fmt.Errorf("nested field %s: %w", k, err)
+		}
+		values[k] = nv
+	}
+	for _, k := range e.nested.required {
+		if values[k] == nil {
+			return nil, fmt.Errorf("nested field %s: %w", k, ErrRequiredFieldMissing)
+		}
+	}
+	return values, nil
+}
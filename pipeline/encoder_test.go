@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+var testEncoderSchema = []RepoSchemaEntry{
+	{Key: "name", ValueType: "string", Required: true},
+	{Key: "count", ValueType: "long"},
+	{Key: "ratio", ValueType: "float"},
+	{Key: "ok", ValueType: "boolean"},
+	{Key: "ts", ValueType: "date"},
+	{Key: "tags", ValueType: "array", ElemType: "long"},
+	{Key: "meta", ValueType: "map", Schema: []RepoSchemaEntry{
+		{Key: "host", ValueType: "string", Required: true},
+		{Key: "at", ValueType: "date"},
+	}},
+	{Key: "extra", ValueType: "map"},
+}
+
+func TestPointEncoder(t *testing.T) {
+	encoder, err := NewPointEncoder(testEncoderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2017, 3, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	data, err := encoder.Encode(Points{
+		{Fields: []PointField{
+			{Key: "name", Value: []byte("a\tb")},
+			{Key: "count", Value: float64(1e6)},
+			{Key: "ratio", Value: 1e6},
+			{Key: "ok", Value: true},
+			{Key: "ts", Value: ts},
+			{Key: "tags", Value: []int{1, 2}},
+			{Key: "meta", Value: map[string]interface{}{"host": "h1", "at": ts}},
+			{Key: "extra", Value: map[string]int{"x": 1}},
+		}},
+		{Fields: []PointField{{Key: "name", Value: "b"}, {Key: "count", Value: "42"}, {Key: "ratio", Value: float32(0.1)}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "name=a\\tb\tcount=1000000\tratio=1000000\tok=true\tts=2017-03-01T08:00:00+08:00\ttags=[1,2]\t" +
+		`meta={"at":"2017-03-01T08:00:00+08:00","host":"h1"}` + "\t" + `extra={"x":1}` + "\n" +
+		"name=b\tcount=42\tratio=0.1"
+	if string(data) != expected {
+		t.Fatalf("expect\n%q\ngot\n%q", expected, data)
+	}
+}
+
+func TestPointEncoderErrors(t *testing.T) {
+	encoder, err := NewPointEncoder(testEncoderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = encoder.Encode(Points{
+		{Fields: []PointField{{Key: "name", Value: "ok"}}},
+		{Fields: []PointField{{Key: "name", Value: "a"}, {Key: "count", Value: 1.5}, {Key: "unknown", Value: 1}}},
+		{Fields: []PointField{{Key: "ts", Value: "yesterday"}, {Key: "tags", Value: []string{"x"}}}},
+		{Fields: []PointField{{Key: "name", Value: "c"}, {Key: "meta", Value: map[string]interface{}{"port": 80}}}},
+	})
+	var pointsErr *PointsError
+	if !errors.As(err, &pointsErr) || !errors.Is(err, reqerr.ErrInvalidArgs) {
+		t.Fatalf("expect PointsError, got %v", err)
+	}
+	var got []string
+	for _, e := range pointsErr.Errors {
+		got = append(got, e.Key)
+	}
+	if expected := []string{"count", "unknown", "ts", "tags", "name", "meta"}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expect errors of %v, got %v", expected, got)
+	}
+	if indexes := pointsErr.Indexes(); !reflect.DeepEqual(indexes, []int{1, 2, 3}) {
+		t.Fatalf("unexpected indexes %v", indexes)
+	}
+	if !errors.Is(pointsErr.Errors[1], ErrUnknownField) || !errors.Is(pointsErr.Errors[4], ErrRequiredFieldMissing) {
+		t.Errorf("expect sentinel errors, got %v and %v", pointsErr.Errors[1], pointsErr.Errors[4])
+	}
+	if !strings.Contains(err.Error(), "and 3 more") {
+		t.Errorf("unexpected message %s", err)
+	}
+
+	if _, err = NewPointEncoder([]RepoSchemaEntry{{Key: "a", ValueType: "long"}, {Key: "a", ValueType: "float"}}); err == nil {
+		t.Fatal("expect error for duplicated key")
+	}
+}
+
+func TestPostDataWithSchema(t *testing.T) {
+	recorder := &postRecorder{posts: map[string][]int{}}
+	c, ts := newTestPipeline(t, recorder)
+	defer ts.Close()
+
+	err := c.PostData(&PostDataInput{
+		RepoName: "repo",
+		Points:   Points{{Fields: []PointField{{Key: "count", Value: 1}}}},
+		Schema:   testEncoderSchema,
+	})
+	var pointsErr *PointsError
+	if !errors.As(err, &pointsErr) {
+		t.Fatalf("expect PointsError, got %v", err)
+	}
+	if recorder.total("repo") != 0 {
+		t.Fatal("expect no request to be sent for invalid points")
+	}
+
+	err = c.PostData(&PostDataInput{
+		RepoName: "repo",
+		Points:   Points{{Fields: []PointField{{Key: "name", Value: "a"}, {Key: "count", Value: 1}}}},
+		Schema:   testEncoderSchema,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorder.total("repo") != 1 {
+		t.Fatalf("expect 1 point, got %d", recorder.total("repo"))
+	}
+}
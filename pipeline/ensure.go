@@ -0,0 +1,94 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qiniu/pandora-go-sdk/base/ensure"
+)
+
+// SchemaConflict 描述一个已存在的字段与期望的字段不兼容，Existing和Desired为RepoSchemaEntry
+type SchemaConflict = ensure.Conflict
+
+// SchemaDiff 是期望的schema与repo当前schema的差异
+type SchemaDiff = ensure.Diff
+
+type EnsureRepoOutput = ensure.Output
+
+// DiffSchema 比较repo当前的schema和期望的schema，返回差异以及合并了缺少字段之后的schema
+func DiffSchema(existing, desired []RepoSchemaEntry) (diff SchemaDiff, merged []RepoSchemaEntry) {
+	merged = diffSchema("", existing, desired, &diff)
+	return
+}
+
+func schemaKeys(entries []RepoSchemaEntry) []string {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+func diffSchema(prefix string, existing, desired []RepoSchemaEntry, diff *SchemaDiff) []RepoSchemaEntry {
+	merged := append([]RepoSchemaEntry(nil), existing...)
+	ensure.DiffKeys(prefix, schemaKeys(existing), schemaKeys(desired), diff, func(i, j int) {
+		d := desired[j]
+		if i < 0 {
+			merged = append(merged, d)
+			return
+		}
+		e := existing[i]
+		var reason string
+		switch {
+		case e.ValueType != d.ValueType:
+			reason = fmt.Sprintf("valtype %s cannot be changed to %s", e.ValueType, d.ValueType)
+		case e.ElemType != d.ElemType:
+			reason = fmt.Sprintf("elemtype %s cannot be changed to %s", e.ElemType, d.ElemType)
+		case e.Required != d.Required:
+			reason = fmt.Sprintf("required %t cannot be changed to %t", e.Required, d.Required)
+		}
+		if reason != "" {
+			diff.Conflicts = append(diff.Conflicts, SchemaConflict{Key: prefix + d.Key, Existing: e, Desired: d, Reason: reason})
+			return
+		}
+		if len(e.Schema) > 0 || len(d.Schema) > 0 {
+			merged[i].Schema = diffSchema(prefix+d.Key+".", e.Schema, d.Schema, diff)
+		}
+	})
+	return merged
+}
+
+func (c *Pipeline) EnsureRepo(input *CreateRepoInput) (*EnsureRepoOutput, error) {
+	return c.EnsureRepoWithContext(context.Background(), input)
+}
+
+// EnsureRepoWithContext 按ensure.Ensure的流程创建repo，或者通过UpdateRepo添加缺少的字段。
+// 存在不兼容的字段时返回reqerr.ErrSchemaConflict，output.Diff中包含差异
+func (c *Pipeline) EnsureRepoWithContext(ctx context.Context, input *CreateRepoInput) (*EnsureRepoOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	var repo *GetRepoOutput
+	var merged []RepoSchemaEntry
+	return ensure.Ensure(ctx, ensure.Repo{
+		Name: input.RepoName,
+		Get: func(ctx context.Context) (err error) {
+			repo, err = c.GetRepoWithContext(ctx, &GetRepoInput{PipelineToken: input.PipelineToken, RepoName: input.RepoName})
+			return
+		},
+		Create: func(ctx context.Context) error {
+			return c.CreateRepoWithContext(ctx, input)
+		},
+		Diff: func() (diff SchemaDiff) {
+			diff, merged = DiffSchema(repo.Schema, input.Schema)
+			return
+		},
+		Update: func(ctx context.Context) error {
+			return c.UpdateRepoWithContext(ctx, &UpdateRepoInput{
+				PipelineToken: input.PipelineToken,
+				RepoName:      input.RepoName,
+				Schema:        merged,
+			})
+		},
+	})
+}
@@ -0,0 +1,46 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSchema(t *testing.T) {
+	existing := []RepoSchemaEntry{
+		{Key: "f1", ValueType: "string", Required: true},
+		{Key: "f2", ValueType: "long"},
+		{Key: "f3", ValueType: "map", Schema: []RepoSchemaEntry{{Key: "n1", ValueType: "string"}}},
+		{Key: "f4", ValueType: "float"},
+	}
+	desired := []RepoSchemaEntry{
+		{Key: "f1", ValueType: "string", Required: true},
+		{Key: "f2", ValueType: "string"},
+		{Key: "f3", ValueType: "map", Schema: []RepoSchemaEntry{{Key: "n1", ValueType: "string"}, {Key: "n2", ValueType: "long"}}},
+		{Key: "f5", ValueType: "boolean"},
+	}
+	diff, merged := DiffSchema(existing, desired)
+	if exp := []string{"f3.n2", "f5"}; !reflect.DeepEqual(diff.Added, exp) {
+		t.Errorf("expect added %v, got %v", exp, diff.Added)
+	}
+	if exp := []string{"f4"}; !reflect.DeepEqual(diff.Extra, exp) {
+		t.Errorf("expect extra %v, got %v", exp, diff.Extra)
+	}
+	if len(diff.Conflicts) != 1 || diff.Conflicts[0].Key != "f2" {
+		t.Errorf("expect conflict on f2, got %v", diff.Conflicts)
+	}
+	expMerged := []RepoSchemaEntry{
+		{Key: "f1", ValueType: "string", Required: true},
+		{Key: "f2", ValueType: "long"},
+		{Key: "f3", ValueType: "map", Schema: []RepoSchemaEntry{{Key: "n1", ValueType: "string"}, {Key: "n2", ValueType: "long"}}},
+		{Key: "f4", ValueType: "float"},
+		{Key: "f5", ValueType: "boolean"},
+	}
+	if !reflect.DeepEqual(merged, expMerged) {
+		t.Errorf("expect merged %v, got %v", expMerged, merged)
+	}
+
+	diff, _ = DiffSchema(existing, existing)
+	if !diff.Empty() {
+		t.Errorf("expect empty diff, got %+v", diff)
+	}
+}
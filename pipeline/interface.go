@@ -1,106 +1,236 @@
 package pipeline
 
 import (
+	"context"
+
 	"github.com/qiniu/pandora-go-sdk/base"
 )
 
 type PipelineAPI interface {
 	CreateGroup(*CreateGroupInput) error
 
+	CreateGroupWithContext(context.Context, *CreateGroupInput) error
+
 	UpdateGroup(*UpdateGroupInput) error
 
+	UpdateGroupWithContext(context.Context, *UpdateGroupInput) error
+
 	StartGroupTask(*StartGroupTaskInput) error
 
+	StartGroupTaskWithContext(context.Context, *StartGroupTaskInput) error
+
 	StopGroupTask(*StopGroupTaskInput) error
 
+	StopGroupTaskWithContext(context.Context, *StopGroupTaskInput) error
+
 	ListGroups(*ListGroupsInput) (*ListGroupsOutput, error)
 
+	ListGroupsWithContext(context.Context, *ListGroupsInput) (*ListGroupsOutput, error)
+
 	GetGroup(*GetGroupInput) (*GetGroupOutput, error)
 
+	GetGroupWithContext(context.Context, *GetGroupInput) (*GetGroupOutput, error)
+
 	DeleteGroup(*DeleteGroupInput) error
 
+	DeleteGroupWithContext(context.Context, *DeleteGroupInput) error
+
 	CreateRepo(*CreateRepoInput) error
 
+	CreateRepoWithContext(context.Context, *CreateRepoInput) error
+
+	EnsureRepo(*CreateRepoInput) (*EnsureRepoOutput, error)
+
+	EnsureRepoWithContext(context.Context, *CreateRepoInput) (*EnsureRepoOutput, error)
+
 	CreateRepoFromDSL(*CreateRepoDSLInput) error
 
+	CreateRepoFromDSLWithContext(context.Context, *CreateRepoDSLInput) error
+
 	UpdateRepo(*UpdateRepoInput) error
 
+	UpdateRepoWithContext(context.Context, *UpdateRepoInput) error
+
 	GetRepo(*GetRepoInput) (*GetRepoOutput, error)
 
+	GetRepoWithContext(context.Context, *GetRepoInput) (*GetRepoOutput, error)
+
 	ListRepos(*ListReposInput) (*ListReposOutput, error)
 
+	ListReposWithContext(context.Context, *ListReposInput) (*ListReposOutput, error)
+
 	DeleteRepo(*DeleteRepoInput) error
 
+	DeleteRepoWithContext(context.Context, *DeleteRepoInput) error
+
 	PostData(*PostDataInput) error
 
+	PostDataWithContext(context.Context, *PostDataInput) error
+
 	PostDataFromFile(*PostDataFromFileInput) error
 
+	PostDataFromFileWithContext(context.Context, *PostDataFromFileInput) error
+
 	PostDataFromReader(*PostDataFromReaderInput) error
 
+	PostDataFromReaderWithContext(context.Context, *PostDataFromReaderInput) error
+
 	PostDataFromBytes(*PostDataFromBytesInput) error
 
+	PostDataFromBytesWithContext(context.Context, *PostDataFromBytesInput) error
+
+	PostDataStream(*PostDataStreamInput) (*PostDataStreamOutput, error)
+
+	PostDataStreamWithContext(context.Context, *PostDataStreamInput) (*PostDataStreamOutput, error)
+
 	UploadPlugin(*UploadPluginInput) error
 
+	UploadPluginWithContext(context.Context, *UploadPluginInput) error
+
 	UploadPluginFromFile(*UploadPluginFromFileInput) error
 
+	UploadPluginFromFileWithContext(context.Context, *UploadPluginFromFileInput) error
+
 	ListPlugins(*ListPluginsInput) (*ListPluginsOutput, error)
 
+	ListPluginsWithContext(context.Context, *ListPluginsInput) (*ListPluginsOutput, error)
+
 	GetPlugin(*GetPluginInput) (*GetPluginOutput, error)
 
+	GetPluginWithContext(context.Context, *GetPluginInput) (*GetPluginOutput, error)
+
 	DeletePlugin(*DeletePluginInput) error
 
+	DeletePluginWithContext(context.Context, *DeletePluginInput) error
+
 	CreateTransform(*CreateTransformInput) error
 
+	CreateTransformWithContext(context.Context, *CreateTransformInput) error
+
 	UpdateTransform(*UpdateTransformInput) error
 
+	UpdateTransformWithContext(context.Context, *UpdateTransformInput) error
+
 	GetTransform(*GetTransformInput) (*GetTransformOutput, error)
 
+	GetTransformWithContext(context.Context, *GetTransformInput) (*GetTransformOutput, error)
+
 	ListTransforms(*ListTransformsInput) (*ListTransformsOutput, error)
 
+	ListTransformsWithContext(context.Context, *ListTransformsInput) (*ListTransformsOutput, error)
+
 	DeleteTransform(*DeleteTransformInput) error
 
+	DeleteTransformWithContext(context.Context, *DeleteTransformInput) error
+
+	VerifyTransform(*VerifyTransformInput) (*VerifyTransformOutput, error)
+
+	VerifyTransformWithContext(context.Context, *VerifyTransformInput) (*VerifyTransformOutput, error)
+
 	CreateExport(*CreateExportInput) error
 
+	CreateExportWithContext(context.Context, *CreateExportInput) error
+
 	UpdateExport(*UpdateExportInput) error
 
+	UpdateExportWithContext(context.Context, *UpdateExportInput) error
+
 	GetExport(*GetExportInput) (*GetExportOutput, error)
 
+	GetExportWithContext(context.Context, *GetExportInput) (*GetExportOutput, error)
+
 	ListExports(*ListExportsInput) (*ListExportsOutput, error)
 
+	ListExportsWithContext(context.Context, *ListExportsInput) (*ListExportsOutput, error)
+
 	DeleteExport(*DeleteExportInput) error
 
+	DeleteExportWithContext(context.Context, *DeleteExportInput) error
+
+	VerifyExport(*VerifyExportInput) error
+
+	VerifyExportWithContext(context.Context, *VerifyExportInput) error
+
 	CreateDatasource(*CreateDatasourceInput) error
 
+	CreateDatasourceWithContext(context.Context, *CreateDatasourceInput) error
+
 	GetDatasource(*GetDatasourceInput) (*GetDatasourceOutput, error)
 
+	GetDatasourceWithContext(context.Context, *GetDatasourceInput) (*GetDatasourceOutput, error)
+
 	ListDatasources() (*ListDatasourcesOutput, error)
 
+	ListDatasourcesWithContext(context.Context) (*ListDatasourcesOutput, error)
+
 	DeleteDatasource(*DeleteDatasourceInput) error
 
+	DeleteDatasourceWithContext(context.Context, *DeleteDatasourceInput) error
+
 	CreateJob(*CreateJobInput) error
 
+	CreateJobWithContext(context.Context, *CreateJobInput) error
+
 	GetJob(*GetJobInput) (*GetJobOutput, error)
 
+	GetJobWithContext(context.Context, *GetJobInput) (*GetJobOutput, error)
+
 	ListJobs(*ListJobsInput) (*ListJobsOutput, error)
 
+	ListJobsWithContext(context.Context, *ListJobsInput) (*ListJobsOutput, error)
+
 	DeleteJob(*DeleteJobInput) error
 
+	DeleteJobWithContext(context.Context, *DeleteJobInput) error
+
 	StartJob(*StartJobInput) error
 
+	StartJobWithContext(context.Context, *StartJobInput) error
+
 	StopJob(*StopJobInput) error
 
+	StopJobWithContext(context.Context, *StopJobInput) error
+
 	GetJobHistory(*GetJobHistoryInput) (*GetJobHistoryOutput, error)
 
+	GetJobHistoryWithContext(context.Context, *GetJobHistoryInput) (*GetJobHistoryOutput, error)
+
 	CreateJobExport(*CreateJobExportInput) error
 
+	CreateJobExportWithContext(context.Context, *CreateJobExportInput) error
+
 	GetJobExport(*GetJobExportInput) (*GetJobExportOutput, error)
 
+	GetJobExportWithContext(context.Context, *GetJobExportInput) (*GetJobExportOutput, error)
+
 	ListJobExports(*ListJobExportsInput) (*ListJobExportsOutput, error)
 
+	ListJobExportsWithContext(context.Context, *ListJobExportsInput) (*ListJobExportsOutput, error)
+
 	DeleteJobExport(*DeleteJobExportInput) error
 
+	DeleteJobExportWithContext(context.Context, *DeleteJobExportInput) error
+
 	RetrieveSchema(*RetrieveSchemaInput) (*RetrieveSchemaOutput, error)
 
+	RetrieveSchemaWithContext(context.Context, *RetrieveSchemaInput) (*RetrieveSchemaOutput, error)
+
+	WaitRepoReady(*WaitRepoReadyInput) (*GetRepoOutput, error)
+
+	WaitRepoReadyWithContext(context.Context, *WaitRepoReadyInput) (*GetRepoOutput, error)
+
+	WaitGroupRunning(*WaitGroupRunningInput) (*GetGroupOutput, error)
+
+	WaitGroupRunningWithContext(context.Context, *WaitGroupRunningInput) (*GetGroupOutput, error)
+
+	WaitJobRunFinished(*WaitJobRunFinishedInput) (*JobHistory, error)
+
+	WaitJobRunFinishedWithContext(context.Context, *WaitJobRunFinishedInput) (*JobHistory, error)
+
+	NewBatchWriter(*BatchWriterConfig) (*BatchWriter, error)
+
+	NewSpoolingWriter(*SpoolingWriterConfig) (*SpoolingWriter, error)
+
 	MakeToken(*base.TokenDesc) (string, error)
 
 	Close() error
@@ -606,6 +606,8 @@ type PostDataInput struct {
 	PipelineToken
 	RepoName string
 	Points   Points
+	// Schema 不为空时按repo的schema校验并编码Points，有点不符合schema时返回*PointsError，不会发送请求
+	Schema []RepoSchemaEntry
 }
 
 type PostDataFromFileInput struct {
@@ -1023,6 +1025,10 @@ func (v *VerifyTransformInput) Validate() (err error) {
 			return
 		}
 	}
+	if v.Spec == nil {
+		err = reqerr.NewInvalidArgs("TransformSpec", "spec should not be nil")
+		return
+	}
 
 	return v.Spec.Validate()
 }
@@ -1372,7 +1378,7 @@ type JobHistory struct {
 
 type GetJobHistoryOutput struct {
 	Total   int64        `json:"total"`
-	History []JobHistory `json:""`
+	History []JobHistory `json:"history"`
 }
 
 type JobExportKodoSpec struct {
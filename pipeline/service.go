@@ -1,11 +1,10 @@
 package pipeline
 
 import (
+	"context"
 	"fmt"
-	"net"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/qiniu/pandora-go-sdk/base"
 	"github.com/qiniu/pandora-go-sdk/base/config"
@@ -47,6 +46,7 @@ func (c *Pipeline) Close() (err error) {
 }
 
 func newClient(c *config.Config) (p *Pipeline, err error) {
+	c = c.ForService(config.ServicePipeline)
 	if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
 		err = fmt.Errorf("endpoint should start with 'http://' or 'https://'")
 		return
@@ -56,30 +56,19 @@ func newClient(c *config.Config) (p *Pipeline, err error) {
 		return
 	}
 
-	var t = &http.Transport{
-		Dial: (&net.Dialer{
-			Timeout:   c.DialTimeout,
-			KeepAlive: 30 * time.Second,
-		}).Dial,
-		ResponseHeaderTimeout: c.ResponseTimeout,
-	}
-
 	p = &Pipeline{
 		Config:     c,
-		HTTPClient: &http.Client{Transport: t},
-	}
-	if c.RequestRateLimit > 0 {
-		p.reqLimit = ratelimit.NewLimiter(c.RequestRateLimit)
-	}
-	if c.FlowRateLimit > 0 {
-		p.flowLimit = ratelimit.NewLimiter(1024 * c.FlowRateLimit)
+		HTTPClient: c.NewHTTPClient(),
 	}
+	p.reqLimit = c.NewRequestLimiter()
+	p.flowLimit = c.NewFlowLimiter()
 	return
 }
 
-func (c *Pipeline) newRequest(op *request.Operation, token string, v interface{}) *request.Request {
+func (c *Pipeline) newRequest(ctx context.Context, op *request.Operation, token string, v interface{}) *request.Request {
 	req := request.New(c.Config, c.HTTPClient, op, token, builder, v)
 	req.Data = v
+	req.SetContext(ctx)
 	return req
 }
 
@@ -172,6 +161,10 @@ func (c *Pipeline) newOperation(opName string, args ...interface{}) *request.Ope
 		method, urlTmpl = base.MethodDelete, "/v2/jobs/%s/exports/%s"
 	case base.OpRetrieveSchema:
 		method, urlTmpl = base.MethodPost, "/v2/schemas"
+	case base.OpVerifyTransform:
+		method, urlTmpl = base.MethodPost, "/v2/verify/transform"
+	case base.OpVerifyExport:
+		method, urlTmpl = base.MethodPost, "/v2/verify/export"
 	default:
 		c.Config.Logger.Errorf("unmatched operation name: %s", opName)
 		return nil
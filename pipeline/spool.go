@@ -0,0 +1,699 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+const (
+	defaultSegmentBytes     int64         = 16 * 1024 * 1024
+	defaultRetryInterval    time.Duration = time.Second
+	defaultMaxRetryInterval time.Duration = time.Minute
+
+	spoolSegmentExt    = ".seg"
+	spoolCursorFile    = "cursor"
+	spoolLockFile      = "lock"
+	spoolRecordHeader  = 8 // 4字节长度 + 4字节crc32
+	spoolMaxRecordSize = 1 << 30
+)
+
+var (
+	ErrSpoolingWriterClosed = errors.New("spooling writer has been closed")
+	ErrSpoolDirLocked       = errors.New("spool dir is being used by another spooling writer")
+
+	// errSpoolRecordCorrupted 表示记录不完整或者已经损坏，重新读取也不会成功
+	errSpoolRecordCorrupted = errors.New("spool record corrupted")
+)
+
+// SpoolingWriterConfig 配置SpoolingWriter的本地spool，零值字段使用默认值
+type SpoolingWriterConfig struct {
+	Dir              string        // spool目录，同一时间只能被一个SpoolingWriter使用，否则NewSpoolingWriter返回ErrSpoolDirLocked
+	SegmentBytes     int64         // 单个segment文件的大小上限，默认为16MB，超过之后写入新的segment
+	MaxBytes         int64         // spool的总大小上限，超过之后删除最老的segment，0表示不限制
+	MaxAge           time.Duration // 最后一次写入超过MaxAge的segment会被删除，0表示不限制
+	RetryInterval    time.Duration // 发送失败之后第一次重试的等待时间，默认为1秒，之后每次翻倍
+	MaxRetryInterval time.Duration // 重试等待时间的上限，默认为1分钟
+	Sync             bool          // 每次写入、创建segment和确认之后对文件和目录调用fsync，进程崩溃和断电时都不会丢失已经写入的数据
+	// OnError 在批次遇到不可重试的错误(例如schema不匹配)时被调用，该批次会被丢弃
+	OnError func(*SpoolError)
+	// PipelineToken 不为空时使用token发送数据，token需要允许向写入的所有repo发送数据
+	PipelineToken
+}
+
+func (c *SpoolingWriterConfig) Validate() (err error) {
+	if c.Dir == "" {
+		return reqerr.NewInvalidArgs("Dir", "spool dir should not be empty")
+	}
+	if c.SegmentBytes < 0 {
+		return reqerr.NewInvalidArgs("SegmentBytes", "segment bytes should not be negative")
+	}
+	if c.MaxBytes < 0 {
+		return reqerr.NewInvalidArgs("MaxBytes", "max bytes should not be negative")
+	}
+	if c.MaxAge < 0 {
+		return reqerr.NewInvalidArgs("MaxAge", "max age should not be negative")
+	}
+	if c.RetryInterval < 0 || c.MaxRetryInterval < 0 {
+		return reqerr.NewInvalidArgs("RetryInterval", "retry interval should not be negative")
+	}
+	return
+}
+
+// SpoolError 表示spool中的一个批次因为不可重试的错误被丢弃，Data是该批次Points.Buffer()的结果
+type SpoolError struct {
+	RepoName string
+	Data     []byte
+	Err      error
+}
+
+func (e *SpoolError) Error() string {
+	return fmt.Sprintf("post spooled data to repo %s failed: %v", e.RepoName, e.Err)
+}
+
+func (e *SpoolError) Unwrap() error {
+	return e.Err
+}
+
+// SpoolStats 是spool的统计信息
+type SpoolStats struct {
+	Segments     int   // 磁盘上的segment数
+	PendingBytes int64 // 尚未被服务端确认的字节数，包含记录头
+	Sent         int64 // 已经发送成功的批次数
+	Dropped      int64 // 因为不可重试的错误被丢弃的批次数
+	Evicted      int64 // 因为MaxBytes或者MaxAge被删除的未确认字节数
+}
+
+type spoolSegment struct {
+	id      uint64
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+type spoolRecord struct {
+	segment  uint64
+	offset   int64
+	next     int64
+	repoName string
+	data     []byte
+}
+
+// SpoolingWriter 把批次先追加到本地磁盘上的segment文件中，再由后台goroutine按写入顺序发送，
+// 发送成功之后才会推进持久化的确认位置。网络中断时数据保留在磁盘上，恢复之后继续发送，
+// 进程重启之后从上一次确认的位置继续发送，因此同一批次可能被发送多次(at-least-once)
+type SpoolingWriter struct {
+	client *Pipeline
+	config SpoolingWriterConfig
+
+	lock     *os.File // spool目录的排他锁
+	mu       sync.Mutex
+	segments []*spoolSegment // 按id排序，cursor总是位于segments[0]
+	active   *os.File        // 正在写入的segment，总是segments中的最后一个
+	cursor   int64           // segments[0]中已经确认的偏移
+	nextID   uint64
+	notify   chan struct{} // 有新数据写入或者确认位置前进时被关闭并替换
+	closed   bool
+	stats    SpoolStats
+
+	reader   *os.File
+	readerID uint64
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewSpoolingWriter 打开cfg.Dir中的spool，并在后台开始发送上一次没有确认的数据
+func (c *Pipeline) NewSpoolingWriter(cfg *SpoolingWriterConfig) (w *SpoolingWriter, err error) {
+	if err = cfg.Validate(); err != nil {
+		return
+	}
+	config := *cfg
+	if config.SegmentBytes == 0 {
+		config.SegmentBytes = defaultSegmentBytes
+	}
+	if config.RetryInterval == 0 {
+		config.RetryInterval = defaultRetryInterval
+	}
+	if config.MaxRetryInterval == 0 {
+		config.MaxRetryInterval = defaultMaxRetryInterval
+	}
+	if config.MaxRetryInterval < config.RetryInterval {
+		config.MaxRetryInterval = config.RetryInterval
+	}
+
+	w = &SpoolingWriter{
+		client: c,
+		config: config,
+		notify: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err = w.open(); err != nil {
+		return nil, err
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	go w.run()
+	return
+}
+
+// open 锁定spool目录，读取已有的segment和确认位置，删除已经确认的segment
+func (w *SpoolingWriter) open() (err error) {
+	if err = os.MkdirAll(w.config.Dir, 0755); err != nil {
+		return
+	}
+	if w.lock, err = lockSpoolDir(filepath.Join(w.config.Dir, spoolLockFile)); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			w.unlock()
+		}
+	}()
+	infos, err := ioutil.ReadDir(w.config.Dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, spoolSegmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, spoolSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		w.segments = append(w.segments, &spoolSegment{id: id, path: filepath.Join(w.config.Dir, name), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(w.segments, func(i, j int) bool { return w.segments[i].id < w.segments[j].id })
+	if n := len(w.segments); n > 0 {
+		w.nextID = w.segments[n-1].id + 1
+	}
+
+	id, offset, err := w.readCursor()
+	if err != nil {
+		return err
+	}
+	for len(w.segments) > 0 && w.segments[0].id < id {
+		if err = os.Remove(w.segments[0].path); err != nil {
+			return err
+		}
+		w.segments = w.segments[1:]
+	}
+	if len(w.segments) > 0 && w.segments[0].id == id {
+		w.cursor = offset
+	}
+	w.stats.Segments = len(w.segments)
+	return nil
+}
+
+func (w *SpoolingWriter) unlock() {
+	path := w.lock.Name()
+	// 先删除再关闭，避免其他SpoolingWriter锁定即将被删除的文件
+	os.Remove(path)
+	w.lock.Close()
+	w.lock = nil
+}
+
+// syncDir 在开启Sync时对spool目录调用fsync，使新建和重命名的文件在断电之后仍然存在
+func (w *SpoolingWriter) syncDir() error {
+	// windows不支持对目录调用fsync
+	if !w.config.Sync || runtime.GOOS == "windows" {
+		return nil
+	}
+	dir, err := os.Open(w.config.Dir)
+	if err != nil {
+		return err
+	}
+	err = dir.Sync()
+	if e := dir.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func (w *SpoolingWriter) readCursor() (id uint64, offset int64, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(w.config.Dir, spoolCursorFile))
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return
+	}
+	if _, err = fmt.Sscanf(string(data), "%d %d", &id, &offset); err != nil {
+		return 0, 0, fmt.Errorf("invalid spool cursor %q, %v", data, err)
+	}
+	return
+}
+
+func (w *SpoolingWriter) writeCursor(id uint64, offset int64) error {
+	path := filepath.Join(w.config.Dir, spoolCursorFile)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(f, "%d %d\n", id, offset); err == nil && w.config.Sync {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return w.syncDir()
+}
+
+// Write 把points序列化之后作为一个批次追加到spool中，返回时数据已经写入磁盘，会在之后被异步发送
+func (w *SpoolingWriter) Write(repoName string, points Points) error {
+	if err := validateRepoName(repoName); err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	return w.append(repoName, points.Buffer())
+}
+
+func (w *SpoolingWriter) append(repoName string, data []byte) error {
+	payload := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(repoName)+len(data))
+	payload = payload[:binary.PutUvarint(payload, uint64(len(repoName)))]
+	payload = append(payload, repoName...)
+	payload = append(payload, data...)
+	if len(payload) > spoolMaxRecordSize {
+		return fmt.Errorf("spool record of %d bytes is too large", len(payload))
+	}
+	record := make([]byte, spoolRecordHeader, spoolRecordHeader+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	record = append(record, payload...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrSpoolingWriterClosed
+	}
+	seg := w.activeSegment()
+	if w.active != nil && seg.size > 0 && seg.size+int64(len(record)) > w.config.SegmentBytes {
+		if err := w.active.Close(); err != nil {
+			return err
+		}
+		w.active = nil
+	}
+	if w.active == nil {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+		seg = w.activeSegment()
+	}
+	if _, err := w.active.Write(record); err != nil {
+		// 去掉写了一半的记录，避免之后的记录无法被读取
+		w.active.Truncate(seg.size)
+		w.active.Seek(seg.size, io.SeekStart)
+		return err
+	}
+	if w.config.Sync {
+		if err := w.active.Sync(); err != nil {
+			return err
+		}
+	}
+	seg.size += int64(len(record))
+	seg.modTime = time.Now()
+	w.evict(seg.modTime)
+	w.broadcast()
+	return nil
+}
+
+// activeSegment 返回正在写入的segment，没有时返回nil
+func (w *SpoolingWriter) activeSegment() *spoolSegment {
+	if w.active == nil {
+		return nil
+	}
+	return w.segments[len(w.segments)-1]
+}
+
+// rotate 创建一个新的segment用于写入，进程重启之后总是写入新的segment，不会追加到可能不完整的旧segment
+func (w *SpoolingWriter) rotate() error {
+	seg := &spoolSegment{id: w.nextID, modTime: time.Now()}
+	seg.path = filepath.Join(w.config.Dir, fmt.Sprintf("%020d%s", seg.id, spoolSegmentExt))
+	f, err := os.OpenFile(seg.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err = w.syncDir(); err != nil {
+		f.Close()
+		os.Remove(seg.path)
+		return err
+	}
+	w.nextID++
+	w.active = f
+	w.segments = append(w.segments, seg)
+	w.stats.Segments = len(w.segments)
+	return nil
+}
+
+// evict 按MaxBytes和MaxAge从最老的segment开始删除，正在写入的segment不会被删除
+func (w *SpoolingWriter) evict(now time.Time) {
+	var total int64
+	for _, seg := range w.segments {
+		total += seg.size
+	}
+	for len(w.segments) > 0 {
+		seg := w.segments[0]
+		if seg == w.activeSegment() {
+			return
+		}
+		expired := w.config.MaxAge > 0 && now.Sub(seg.modTime) > w.config.MaxAge
+		if !expired && (w.config.MaxBytes == 0 || total <= w.config.MaxBytes) {
+			return
+		}
+		lost := seg.size - w.cursor
+		if err := w.removeFirst(); err != nil {
+			w.logf("spooling writer evict segment %s failed, err: %v", seg.path, err)
+			return
+		}
+		total -= seg.size
+		if lost > 0 {
+			w.stats.Evicted += lost
+			w.logf("spooling writer evicted segment %s, %d bytes unsent", seg.path, lost)
+		}
+	}
+}
+
+// removeFirst 删除segments[0]并把确认位置移动到下一个segment的开头
+func (w *SpoolingWriter) removeFirst() error {
+	seg := w.segments[0]
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	w.segments = w.segments[1:]
+	w.stats.Segments = len(w.segments)
+	w.cursor = 0
+	if len(w.segments) > 0 {
+		return w.writeCursor(w.segments[0].id, 0)
+	}
+	return w.writeCursor(w.nextID, 0)
+}
+
+func (w *SpoolingWriter) broadcast() {
+	close(w.notify)
+	w.notify = make(chan struct{})
+}
+
+func (w *SpoolingWriter) logf(format string, args ...interface{}) {
+	if w.client.Config.Logger != nil {
+		w.client.Config.Logger.Errorf(format, args...)
+	}
+}
+
+// pending 返回确认位置之后的字节数
+func (w *SpoolingWriter) pending() (n int64) {
+	for _, seg := range w.segments {
+		n += seg.size
+	}
+	return n - w.cursor
+}
+
+// Stats 返回spool当前的统计信息
+func (w *SpoolingWriter) Stats() SpoolStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := w.stats
+	stats.PendingBytes = w.pending()
+	return stats
+}
+
+// Flush 等待spool中所有的数据都被服务端确认，ctx被取消时返回ctx.Err()
+func (w *SpoolingWriter) Flush(ctx context.Context) error {
+	for {
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			return ErrSpoolingWriterClosed
+		}
+		n, notify := w.pending(), w.notify
+		w.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close 停止写入和发送，未确认的数据保留在磁盘上，下一次打开同一个目录时继续发送
+func (w *SpoolingWriter) Close() (err error) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrSpoolingWriterClosed
+	}
+	w.closed = true
+	if w.active != nil {
+		err = w.active.Close()
+		w.active = nil
+	}
+	w.broadcast()
+	w.mu.Unlock()
+
+	w.cancel()
+	<-w.done
+	if w.reader != nil {
+		w.reader.Close()
+	}
+	w.unlock()
+	return
+}
+
+func (w *SpoolingWriter) run() {
+	defer close(w.done)
+	interval := w.config.RetryInterval
+	for {
+		record, notify, err := w.next()
+		if err != nil {
+			w.logf("spooling writer read spool failed, retry after %v, err: %v", interval, err)
+			if !w.wait(interval) {
+				return
+			}
+			interval = w.backoff(interval)
+			continue
+		}
+		if record == nil {
+			select {
+			case <-notify:
+				continue
+			case <-w.ctx.Done():
+				return
+			}
+		}
+		err = w.client.PostDataFromBytesWithContext(w.ctx, &PostDataFromBytesInput{
+			RepoName:      record.repoName,
+			Buffer:        record.data,
+			PipelineToken: w.config.PipelineToken,
+		})
+		if w.ctx.Err() != nil {
+			return
+		}
+		if err != nil && spoolRetryable(err) {
+			w.logf("spooling writer post data to repo %s failed, retry after %v, err: %v", record.repoName, interval, err)
+			if !w.wait(interval) {
+				return
+			}
+			interval = w.backoff(interval)
+			w.mu.Lock()
+			w.evict(time.Now())
+			w.mu.Unlock()
+			continue
+		}
+		interval = w.config.RetryInterval
+		if err != nil && w.config.OnError != nil {
+			w.config.OnError(&SpoolError{RepoName: record.repoName, Data: record.data, Err: err})
+		} else if err != nil {
+			w.logf("spooling writer drop data to repo %s, err: %v", record.repoName, err)
+		}
+		w.ack(record, err == nil)
+	}
+}
+
+// wait 等待d，返回false表示SpoolingWriter已经关闭
+func (w *SpoolingWriter) wait(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
+
+// backoff 返回下一次重试的等待时间
+func (w *SpoolingWriter) backoff(interval time.Duration) time.Duration {
+	if interval *= 2; interval > w.config.MaxRetryInterval {
+		interval = w.config.MaxRetryInterval
+	}
+	return interval
+}
+
+// next 返回确认位置上的记录，没有数据时返回nil和等待新数据的channel。
+// 记录不完整或者损坏时跳过segment剩余的部分，其他读取错误(例如打开文件失败)返回给调用方稍后重试
+func (w *SpoolingWriter) next() (*spoolRecord, chan struct{}, error) {
+	for {
+		w.mu.Lock()
+		if len(w.segments) == 0 {
+			notify := w.notify
+			w.mu.Unlock()
+			return nil, notify, nil
+		}
+		seg, offset := w.segments[0], w.cursor
+		if offset >= seg.size {
+			notify := w.notify
+			if seg != w.activeSegment() && len(w.segments) > 1 {
+				// 已经发送完的旧segment
+				if err := w.removeFirst(); err != nil {
+					w.logf("spooling writer remove segment %s failed, err: %v", seg.path, err)
+				}
+				w.broadcast()
+				w.mu.Unlock()
+				continue
+			}
+			w.mu.Unlock()
+			return nil, notify, nil
+		}
+		w.mu.Unlock()
+
+		record, err := w.read(seg, offset)
+		if err == nil {
+			return record, nil, nil
+		}
+		w.mu.Lock()
+		if len(w.segments) == 0 || w.segments[0] != seg || w.cursor != offset {
+			// 读取期间segment被删除或者确认位置发生了变化，重新读取
+			w.mu.Unlock()
+			continue
+		}
+		if !errors.Is(err, errSpoolRecordCorrupted) {
+			w.mu.Unlock()
+			w.closeReader()
+			return nil, nil, err
+		}
+		// 进程崩溃时segment末尾可能有不完整的记录，跳过segment剩余的部分
+		w.logf("spooling writer skip %d bytes of segment %s, err: %v", seg.size-offset, seg.path, err)
+		w.stats.Evicted += seg.size - offset
+		w.cursor = seg.size
+		if seg == w.activeSegment() {
+			// 正在写入的segment中不应该出现不完整的记录，之后写入新的segment
+			w.active.Close()
+			w.active = nil
+		}
+		w.mu.Unlock()
+	}
+}
+
+// closeReader 关闭读取segment的文件，下一次读取时重新打开
+func (w *SpoolingWriter) closeReader() {
+	if w.reader != nil {
+		w.reader.Close()
+		w.reader = nil
+	}
+}
+
+// read 读取segment中offset位置的记录，记录不完整或者损坏时返回的错误包含errSpoolRecordCorrupted
+func (w *SpoolingWriter) read(seg *spoolSegment, offset int64) (*spoolRecord, error) {
+	if w.reader == nil || w.readerID != seg.id {
+		w.closeReader()
+		f, err := os.Open(seg.path)
+		if err != nil {
+			return nil, err
+		}
+		w.reader, w.readerID = f, seg.id
+	}
+	header := make([]byte, spoolRecordHeader)
+	if _, err := w.reader.ReadAt(header, offset); err != nil {
+		return nil, fmt.Errorf("read record header failed, %w", spoolReadError(err))
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > spoolMaxRecordSize || offset+spoolRecordHeader+int64(size) > seg.size {
+		return nil, fmt.Errorf("%w: invalid record size %d", errSpoolRecordCorrupted, size)
+	}
+	payload := make([]byte, size)
+	if _, err := w.reader.ReadAt(payload, offset+spoolRecordHeader); err != nil {
+		return nil, fmt.Errorf("read record failed, %w", spoolReadError(err))
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, fmt.Errorf("%w: record checksum mismatch", errSpoolRecordCorrupted)
+	}
+	n, i := binary.Uvarint(payload)
+	if i <= 0 || uint64(len(payload)-i) < n {
+		return nil, fmt.Errorf("%w: invalid record repo name", errSpoolRecordCorrupted)
+	}
+	return &spoolRecord{
+		segment:  seg.id,
+		offset:   offset,
+		next:     offset + spoolRecordHeader + int64(size),
+		repoName: string(payload[i : i+int(n)]),
+		data:     payload[i+int(n):],
+	}, nil
+}
+
+// spoolReadError 把文件提前结束(记录被截断)转换为errSpoolRecordCorrupted，其他错误原样返回
+func spoolReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %v", errSpoolRecordCorrupted, err)
+	}
+	return err
+}
+
+// ack 推进确认位置，记录所在的segment已经被删除时不做任何事
+func (w *SpoolingWriter) ack(record *spoolRecord, sent bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if sent {
+		w.stats.Sent++
+	} else {
+		w.stats.Dropped++
+	}
+	if len(w.segments) == 0 || w.segments[0].id != record.segment || w.cursor != record.offset {
+		return
+	}
+	w.cursor = record.next
+	if err := w.writeCursor(record.segment, record.next); err != nil {
+		w.logf("spooling writer save cursor failed, err: %v", err)
+	}
+	w.broadcast()
+}
+
+// spoolRetryable 判断发送失败的批次是否需要留在spool中重试：网络错误、5xx、429以及鉴权失败都会重试，
+// 参数错误和其他4xx错误重试也不会成功
+func spoolRetryable(err error) bool {
+	var reqErr *reqerr.RequestError
+	if !errors.As(err, &reqErr) {
+		return true
+	}
+	if reqErr.ErrorType == reqerr.InvalidArgs {
+		return false
+	}
+	switch reqErr.StatusCode {
+	case 0, 401, 403, 408, 429:
+		return true
+	}
+	return reqErr.StatusCode >= 500
+}
@@ -0,0 +1,36 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package pipeline
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockSpoolDir 对lock文件加flock排他锁，进程退出时锁会被系统释放，不会因为崩溃留下无法打开的spool目录
+func lockSpoolDir(path string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			if err == syscall.EWOULDBLOCK {
+				return nil, ErrSpoolDirLocked
+			}
+			return nil, err
+		}
+		// 加锁之前文件可能已经被持有锁的SpoolingWriter在Close时删除
+		locked, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if current, err := os.Stat(path); err == nil && os.SameFile(locked, current) {
+			return f, nil
+		}
+		f.Close()
+	}
+}
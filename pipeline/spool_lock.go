@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package pipeline
+
+import "os"
+
+// lockSpoolDir 通过独占创建lock文件锁定spool目录。进程崩溃之后lock文件会保留，需要确认没有其他进程使用之后手动删除
+func lockSpoolDir(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if os.IsExist(err) {
+		return nil, ErrSpoolDirLocked
+	}
+	return f, err
+}
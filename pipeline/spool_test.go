@@ -0,0 +1,291 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+)
+
+// spoolServer 按顺序记录收到的请求体，status不为200时返回对应的错误
+type spoolServer struct {
+	sync.Mutex
+	status int
+	bodies []string
+	auth   []string
+}
+
+func (s *spoolServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.Lock()
+	defer s.Unlock()
+	if s.status != http.StatusOK {
+		w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+		w.WriteHeader(s.status)
+		w.Write([]byte(`{"error":"E18101: service unavailable"}`))
+		return
+	}
+	s.bodies = append(s.bodies, string(body))
+	s.auth = append(s.auth, r.Header.Get(base.HTTPHeaderAuthorization))
+}
+
+func (s *spoolServer) setStatus(status int) {
+	s.Lock()
+	s.status = status
+	s.Unlock()
+}
+
+func (s *spoolServer) received() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func newTestSpoolingWriter(t *testing.T, c *Pipeline, cfg SpoolingWriterConfig) *SpoolingWriter {
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = 5 * time.Millisecond
+	}
+	w, err := c.NewSpoolingWriter(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func flushSpool(t *testing.T, w *SpoolingWriter) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("flush spool failed, stats %+v, err: %v", w.Stats(), err)
+	}
+}
+
+func testBatch(i int) (Points, string) {
+	points := Points{testPoint(i)}
+	return points, string(points.Buffer())
+}
+
+func TestSpoolingWriterReplay(t *testing.T) {
+	s := &spoolServer{status: http.StatusServiceUnavailable}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+	w := newTestSpoolingWriter(t, c, SpoolingWriterConfig{Dir: t.TempDir(), SegmentBytes: 64})
+	defer w.Close()
+
+	var expected []string
+	for i := 0; i < 5; i++ {
+		points, body := testBatch(i)
+		if err := w.Write("repo", points); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, body)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if stats := w.Stats(); stats.PendingBytes == 0 || stats.Segments < 2 || stats.Sent != 0 {
+		t.Fatalf("expect data to stay in spool, got %+v", stats)
+	}
+
+	s.setStatus(http.StatusOK)
+	flushSpool(t, w)
+	if got := s.received(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expect batches in order %v, got %v", expected, got)
+	}
+	if stats := w.Stats(); stats.Sent != 5 || stats.Segments != 1 {
+		t.Errorf("expect consumed segments to be removed, got %+v", stats)
+	}
+}
+
+func TestSpoolingWriterRestart(t *testing.T) {
+	s := &spoolServer{status: http.StatusServiceUnavailable}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+	dir := t.TempDir()
+
+	w := newTestSpoolingWriter(t, c, SpoolingWriterConfig{Dir: dir})
+	var expected []string
+	for i := 0; i < 3; i++ {
+		points, body := testBatch(i)
+		if err := w.Write("repo", points); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, body)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("repo", Points{testPoint(0)}); err != ErrSpoolingWriterClosed {
+		t.Fatalf("expect ErrSpoolingWriterClosed, got %v", err)
+	}
+	// 模拟进程在写入记录的过程中崩溃
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentExt))
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 1})
+	f.Close()
+
+	s.setStatus(http.StatusOK)
+	w = newTestSpoolingWriter(t, c, SpoolingWriterConfig{Dir: dir})
+	points, body := testBatch(3)
+	if err := w.Write("repo", points); err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected, body)
+	flushSpool(t, w)
+	w.Close()
+	if got := s.received(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expect batches %v after restart, got %v", expected, got)
+	}
+
+	// 已经确认的数据不会被再次发送
+	w = newTestSpoolingWriter(t, c, SpoolingWriterConfig{Dir: dir})
+	flushSpool(t, w)
+	w.Close()
+	if got := s.received(); len(got) != len(expected) {
+		t.Fatalf("expect no data to be resent, got %v", got)
+	}
+}
+
+func TestSpoolingWriterEviction(t *testing.T) {
+	s := &spoolServer{status: http.StatusServiceUnavailable}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+	points, body := testBatch(0)
+	record := int64(spoolRecordHeader + 1 + len("repo") + len(body))
+	w := newTestSpoolingWriter(t, c, SpoolingWriterConfig{Dir: t.TempDir(), SegmentBytes: record, MaxBytes: 3 * record})
+	defer w.Close()
+
+	var expected []string
+	for i := 0; i < 6; i++ {
+		points, body = testBatch(i)
+		if err := w.Write("repo", points); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, body)
+	}
+	if stats := w.Stats(); stats.Segments != 3 || stats.Evicted != 3*record {
+		t.Fatalf("expect oldest segments to be evicted, got %+v", stats)
+	}
+
+	s.setStatus(http.StatusOK)
+	flushSpool(t, w)
+	if got := s.received(); !reflect.DeepEqual(got, expected[3:]) {
+		t.Fatalf("expect newest batches %v, got %v", expected[3:], got)
+	}
+}
+
+func TestSpoolingWriterDropUnretryable(t *testing.T) {
+	s := &spoolServer{status: http.StatusBadRequest}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+	errCh := make(chan *SpoolError, 1)
+	w := newTestSpoolingWriter(t, c, SpoolingWriterConfig{
+		Dir:     t.TempDir(),
+		OnError: func(e *SpoolError) { errCh <- e },
+	})
+	defer w.Close()
+
+	points, body := testBatch(0)
+	if err := w.Write("repo", points); err != nil {
+		t.Fatal(err)
+	}
+	flushSpool(t, w)
+	e := <-errCh
+	if e.RepoName != "repo" || string(e.Data) != body || e.Err == nil {
+		t.Fatalf("unexpected spool error %+v", e)
+	}
+	if stats := w.Stats(); stats.Dropped != 1 || stats.Sent != 0 {
+		t.Fatalf("expect batch to be dropped, got %+v", stats)
+	}
+}
+
+func TestSpoolingWriterDirLocked(t *testing.T) {
+	s := &spoolServer{status: http.StatusOK}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+	dir := t.TempDir()
+
+	w := newTestSpoolingWriter(t, c, SpoolingWriterConfig{Dir: dir, Sync: true})
+	if _, err := c.NewSpoolingWriter(&SpoolingWriterConfig{Dir: dir}); err != ErrSpoolDirLocked {
+		t.Fatalf("expect ErrSpoolDirLocked, got %v", err)
+	}
+	points, body := testBatch(0)
+	if err := w.Write("repo", points); err != nil {
+		t.Fatal(err)
+	}
+	flushSpool(t, w)
+	w.Close()
+	if got := s.received(); !reflect.DeepEqual(got, []string{body}) {
+		t.Fatalf("expect batch to be sent, got %v", got)
+	}
+
+	// Close之后可以再次打开
+	w = newTestSpoolingWriter(t, c, SpoolingWriterConfig{Dir: dir})
+	w.Close()
+}
+
+func TestSpoolingWriterReadRetry(t *testing.T) {
+	w := &SpoolingWriter{
+		config: SpoolingWriterConfig{Dir: t.TempDir(), SegmentBytes: defaultSegmentBytes},
+		notify: make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.unlock()
+	if err := w.append("repo", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	defer w.active.Close()
+
+	// 打开segment失败不是记录损坏，不能跳过segment中的数据
+	seg := w.segments[0]
+	if err := os.Rename(seg.path, seg.path+".bak"); err != nil {
+		t.Fatal(err)
+	}
+	if record, _, err := w.next(); record != nil || err == nil || errors.Is(err, errSpoolRecordCorrupted) {
+		t.Fatalf("expect transient read error, got %v %v", record, err)
+	}
+	if stats := w.Stats(); stats.Evicted != 0 || stats.PendingBytes != seg.size {
+		t.Fatalf("expect data to stay in spool, got %+v", stats)
+	}
+	if err := os.Rename(seg.path+".bak", seg.path); err != nil {
+		t.Fatal(err)
+	}
+	record, _, err := w.next()
+	if err != nil || record == nil || record.repoName != "repo" || string(record.data) != "data" {
+		t.Fatalf("expect record after retry, got %+v %v", record, err)
+	}
+	w.closeReader()
+}
+
+func TestSpoolingWriterToken(t *testing.T) {
+	s := &spoolServer{status: http.StatusOK}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+	w := newTestSpoolingWriter(t, c, SpoolingWriterConfig{
+		Dir:           t.TempDir(),
+		PipelineToken: PipelineToken{Token: "Pandora ak:sign:desc"},
+	})
+	defer w.Close()
+
+	points, _ := testBatch(0)
+	if err := w.Write("repo", points); err != nil {
+		t.Fatal(err)
+	}
+	flushSpool(t, w)
+	s.Lock()
+	defer s.Unlock()
+	if !reflect.DeepEqual(s.auth, []string{"Pandora ak:sign:desc"}) {
+		t.Fatalf("expect spooled data to be posted with token, got %v", s.auth)
+	}
+}
@@ -0,0 +1,261 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+const (
+	defaultStreamChunkSize   int64 = 2 * 1024 * 1024
+	defaultStreamConcurrency int   = 1
+)
+
+// PostDataStreamInput 描述一次流式上传，数据按行切分成多个请求发送，不会一次性读入内存。
+// Reader和FilePath只能设置一个
+type PostDataStreamInput struct {
+	PipelineToken
+	RepoName    string
+	Reader      io.Reader
+	FilePath    string
+	Offset      int64 // 从该偏移开始上传，通常是上一次失败时StreamError中的Offset
+	ChunkSize   int64 // 单个请求最多包含的字节数(压缩之前)，默认为2MB
+	Concurrency int   // 同时发送的请求数，默认为1，即按顺序发送
+	// OnProgress 在每次已确认的偏移前进时被调用，调用是串行的
+	OnProgress func(StreamProgress)
+}
+
+func (i *PostDataStreamInput) Validate() (err error) {
+	if err = validateRepoName(i.RepoName); err != nil {
+		return
+	}
+	if (i.Reader == nil) == (i.FilePath == "") {
+		return reqerr.NewInvalidArgs("Reader", "exactly one of reader and file path should be set")
+	}
+	if i.Offset < 0 {
+		return reqerr.NewInvalidArgs("Offset", "offset should not be negative")
+	}
+	if i.ChunkSize < 0 {
+		return reqerr.NewInvalidArgs("ChunkSize", "chunk size should not be negative")
+	}
+	if i.Concurrency < 0 {
+		return reqerr.NewInvalidArgs("Concurrency", "concurrency should not be negative")
+	}
+	return
+}
+
+// StreamProgress 是流式上传的进度，Offset之前的数据都已经被服务端确认
+type StreamProgress struct {
+	Offset    int64 // 相对于整个输入的偏移，包含PostDataStreamInput.Offset
+	BytesSent int64 // 本次上传已确认的字节数
+	Chunks    int   // 本次上传已确认的请求数
+}
+
+type PostDataStreamOutput struct {
+	StreamProgress
+}
+
+// StreamError 表示流式上传失败，Offset之前的数据都已经被服务端确认，
+// 把Offset设置为PostDataStreamInput.Offset可以从失败的位置继续上传。
+// Concurrency大于1时，Offset之后的部分请求可能已经成功，继续上传会导致这部分数据重复
+type StreamError struct {
+	RepoName string
+	Offset   int64
+	Err      error
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("post data stream to repo %s failed at offset %d: %v", e.RepoName, e.Offset, e.Err)
+}
+
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
+func (c *Pipeline) PostDataStream(input *PostDataStreamInput) (*PostDataStreamOutput, error) {
+	return c.PostDataStreamWithContext(context.Background(), input)
+}
+
+func (c *Pipeline) PostDataStreamWithContext(ctx context.Context, input *PostDataStreamInput) (output *PostDataStreamOutput, err error) {
+	if err = input.Validate(); err != nil {
+		return
+	}
+	reader := input.Reader
+	if input.FilePath != "" {
+		var file *os.File
+		if file, err = os.Open(input.FilePath); err != nil {
+			return
+		}
+		defer file.Close()
+		reader = file
+	}
+	if input.Offset > 0 {
+		if seeker, ok := reader.(io.Seeker); ok {
+			_, err = seeker.Seek(input.Offset, io.SeekStart)
+		} else {
+			_, err = io.CopyN(ioutil.Discard, reader, input.Offset)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("skip to offset %d failed, %v", input.Offset, err)
+		}
+	}
+	chunkSize := input.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = defaultStreamChunkSize
+	}
+	concurrency := input.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultStreamConcurrency
+	}
+
+	s := &stream{
+		client:  c,
+		input:   input,
+		scanner: &chunkScanner{reader: bufio.NewReader(reader), offset: input.Offset, size: chunkSize},
+		done:    map[int]*streamChunk{},
+		failed:  -1,
+	}
+	s.progress.Offset = input.Offset
+	return s.run(ctx, concurrency)
+}
+
+type streamChunk struct {
+	seq        int
+	start, end int64
+	data       []byte
+}
+
+type stream struct {
+	client  *Pipeline
+	input   *PostDataStreamInput
+	scanner *chunkScanner
+
+	lock     sync.Mutex
+	done     map[int]*streamChunk // 已经成功但是前面还有未确认的请求
+	next     int
+	progress StreamProgress
+	err      error
+	failed   int
+}
+
+func (s *stream) run(ctx context.Context, concurrency int) (*PostDataStreamOutput, error) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for seq := 0; ; seq++ {
+		// 先拿到发送的名额再检查是否已经失败，避免在前面的请求失败之后继续发送
+		sem <- struct{}{}
+		s.lock.Lock()
+		stop := s.err != nil
+		s.lock.Unlock()
+		if stop {
+			break
+		}
+		chunk, err := s.scanner.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.fail(seq, err)
+			break
+		}
+		chunk.seq = seq
+		wg.Add(1)
+		go func(chunk *streamChunk) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			s.ack(chunk, s.client.PostDataFromBytesWithContext(ctx, &PostDataFromBytesInput{
+				PipelineToken: s.input.PipelineToken,
+				RepoName:      s.input.RepoName,
+				Buffer:        bytes.TrimSuffix(chunk.data, []byte("\n")),
+			}))
+		}(chunk)
+	}
+	wg.Wait()
+
+	if s.err != nil {
+		return &PostDataStreamOutput{StreamProgress: s.progress}, &StreamError{RepoName: s.input.RepoName, Offset: s.progress.Offset, Err: s.err}
+	}
+	return &PostDataStreamOutput{StreamProgress: s.progress}, nil
+}
+
+// fail 记录序号最小的失败请求的错误
+func (s *stream) fail(seq int, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err == nil || seq < s.failed {
+		s.err, s.failed = err, seq
+	}
+}
+
+// ack 记录一个请求的结果，并把已确认的偏移推进到连续成功的请求之后
+func (s *stream) ack(chunk *streamChunk, err error) {
+	if err != nil {
+		s.fail(chunk.seq, err)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.done[chunk.seq] = chunk
+	for {
+		c, ok := s.done[s.next]
+		if !ok {
+			return
+		}
+		delete(s.done, s.next)
+		s.next++
+		s.progress.Offset = c.end
+		s.progress.BytesSent += c.end - c.start
+		s.progress.Chunks++
+		if s.input.OnProgress != nil {
+			s.input.OnProgress(s.progress)
+		}
+	}
+}
+
+// chunkScanner 按行读取数据，每次返回不超过size字节的完整行
+type chunkScanner struct {
+	reader  *bufio.Reader
+	offset  int64
+	size    int64
+	pending []byte // 上一次读到但是放不下的行
+	eof     bool
+}
+
+func (s *chunkScanner) next() (*streamChunk, error) {
+	data := s.pending
+	s.pending = nil
+	for !s.eof {
+		line, err := s.reader.ReadBytes('\n')
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if int64(len(line)) > s.size {
+			return nil, fmt.Errorf("line at offset %d is larger than chunk size %d", s.offset+int64(len(data)), s.size)
+		}
+		if int64(len(data)+len(line)) > s.size {
+			s.pending = line
+			break
+		}
+		data = append(data, line...)
+	}
+	if len(data) == 0 {
+		return nil, io.EOF
+	}
+	chunk := &streamChunk{start: s.offset, end: s.offset + int64(len(data)), data: data}
+	s.offset = chunk.end
+	return chunk, nil
+}
@@ -0,0 +1,139 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+type streamRecorder struct {
+	sync.Mutex
+	failAt int // 第failAt个请求返回500，从1开始计数
+	count  int
+	bodies []string
+}
+
+func (s *streamRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var reader io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		reader, _ = gzip.NewReader(r.Body)
+	}
+	body, _ := ioutil.ReadAll(reader)
+	s.Lock()
+	defer s.Unlock()
+	s.count++
+	if s.count == s.failAt {
+		w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"E9000: internal error"}`))
+		return
+	}
+	s.bodies = append(s.bodies, string(body))
+}
+
+func streamData(lines int) string {
+	var buf bytes.Buffer
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&buf, "f1=value_%03d\n", i)
+	}
+	return buf.String()
+}
+
+func TestPostDataStream(t *testing.T) {
+	rec := &streamRecorder{}
+	c, ts := newTestPipeline(t, rec)
+	defer ts.Close()
+	c.Config.Gzip = true
+
+	data := streamData(100) // 每行13字节
+	var progress []StreamProgress
+	output, err := c.PostDataStream(&PostDataStreamInput{
+		RepoName:    "repo",
+		Reader:      strings.NewReader(data),
+		ChunkSize:   140,
+		Concurrency: 3,
+		OnProgress:  func(p StreamProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Offset != int64(len(data)) || output.BytesSent != int64(len(data)) || output.Chunks != 10 {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	if len(progress) != 10 || progress[9].Offset != int64(len(data)) {
+		t.Fatalf("unexpected progress %+v", progress)
+	}
+	var lines int
+	for _, body := range rec.bodies {
+		if strings.HasSuffix(body, "\n") || strings.Count(body, "\n") != 9 {
+			t.Errorf("chunk should contain 10 whole lines, got %q", body)
+		}
+		lines += strings.Count(body, "\n") + 1
+	}
+	if lines != 100 {
+		t.Fatalf("expect 100 lines, got %d", lines)
+	}
+}
+
+func TestPostDataStreamResume(t *testing.T) {
+	rec := &streamRecorder{failAt: 3}
+	c, ts := newTestPipeline(t, rec)
+	defer ts.Close()
+
+	data := streamData(50)
+	input := &PostDataStreamInput{
+		RepoName:  "repo",
+		Reader:    io.MultiReader(strings.NewReader(data)), // 不支持Seek
+		ChunkSize: 100,
+	}
+	_, err := c.PostDataStream(input)
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("expect StreamError, got %v", err)
+	}
+	var reqErr *reqerr.RequestError
+	if streamErr.Offset != 2*7*13 || !errors.As(err, &reqErr) || reqErr.StatusCode != 500 {
+		t.Fatalf("unexpected error %+v", streamErr)
+	}
+
+	input.Reader = io.MultiReader(strings.NewReader(data))
+	input.Offset = streamErr.Offset
+	output, err := c.PostDataStream(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Offset != int64(len(data)) || output.BytesSent != int64(len(data))-streamErr.Offset {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	if got := strings.Join(rec.bodies, "\n") + "\n"; got != data {
+		t.Fatalf("expect every line sent exactly once, got %q", got)
+	}
+}
+
+func TestPostDataStreamLongLine(t *testing.T) {
+	c, ts := newTestPipeline(t, &streamRecorder{})
+	defer ts.Close()
+
+	_, err := c.PostDataStream(&PostDataStreamInput{
+		RepoName:  "repo",
+		Reader:    strings.NewReader("f1=a\nf1=" + strings.Repeat("b", 100) + "\n"),
+		ChunkSize: 50,
+	})
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || streamErr.Offset != 0 {
+		t.Fatalf("expect StreamError at offset 0, got %v", err)
+	}
+	if err = (&PostDataStreamInput{RepoName: "repo"}).Validate(); err == nil {
+		t.Fatal("expect error without reader and file path")
+	}
+}
@@ -0,0 +1,193 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base/reqerr"
+)
+
+const (
+	defaultWaitInterval = time.Second
+	defaultWaitTimeout  = 5 * time.Minute
+)
+
+const (
+	GroupStatusRunning = "Running"
+	GroupStatusError   = "Error"
+	GroupStatusFailed  = "Failed"
+
+	JobRunStatusRunning    = "Running"
+	JobRunStatusSuccessful = "Successful"
+	JobRunStatusFailed     = "Failed"
+	JobRunStatusCanceled   = "Canceled"
+)
+
+// ErrWaitTimeout 表示在Timeout之内资源没有达到期望的状态
+var ErrWaitTimeout = errors.New("wait timeout")
+
+// WaitFailedError 表示资源进入了失败的终止状态，继续等待也不会达到期望的状态
+type WaitFailedError struct {
+	Resource string
+	Name     string
+	Status   string
+	Message  string
+}
+
+func (e *WaitFailedError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s %s is in terminal status %s", e.Resource, e.Name, e.Status)
+	}
+	return fmt.Sprintf("%s %s is in terminal status %s: %s", e.Resource, e.Name, e.Status, e.Message)
+}
+
+// WaitOption 控制轮询的间隔和总的等待时间，为0时分别使用1秒和5分钟
+type WaitOption struct {
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+type WaitRepoReadyInput struct {
+	PipelineToken
+	RepoName string
+	WaitOption
+}
+
+type WaitGroupRunningInput struct {
+	PipelineToken
+	GroupName string
+	WaitOption
+}
+
+type WaitJobRunFinishedInput struct {
+	PipelineToken
+	JobName string
+	// RunId 为0时等待最近一次运行
+	RunId int64
+	WaitOption
+}
+
+// wait 每隔Interval调用一次poll，直到poll返回done或者error；超时之后返回ErrWaitTimeout
+func (o WaitOption) wait(ctx context.Context, resource, name string, poll func(context.Context) (done bool, status string, err error)) error {
+	interval, timeout := o.Interval, o.Timeout
+	if interval <= 0 {
+		interval = defaultWaitInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		done, status, err := poll(waitCtx)
+		if done || (err != nil && waitCtx.Err() == nil) {
+			return err
+		}
+		if waitCtx.Err() == nil {
+			select {
+			case <-ticker.C:
+				continue
+			case <-waitCtx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %s %s, last status %q", ErrWaitTimeout, resource, name, status)
+	}
+}
+
+func (c *Pipeline) WaitRepoReady(input *WaitRepoReadyInput) (*GetRepoOutput, error) {
+	return c.WaitRepoReadyWithContext(context.Background(), input)
+}
+
+// WaitRepoReadyWithContext 等待repo创建完成，repo处于创建中(E18124)时继续轮询
+func (c *Pipeline) WaitRepoReadyWithContext(ctx context.Context, input *WaitRepoReadyInput) (output *GetRepoOutput, err error) {
+	err = input.wait(ctx, "repo", input.RepoName, func(ctx context.Context) (bool, string, error) {
+		var err error
+		output, err = c.GetRepoWithContext(ctx, &GetRepoInput{PipelineToken: input.PipelineToken, RepoName: input.RepoName})
+		if errors.Is(err, reqerr.ErrRepoInCreating) {
+			return false, "creating", nil
+		}
+		return err == nil, "", err
+	})
+	return
+}
+
+func (c *Pipeline) WaitGroupRunning(input *WaitGroupRunningInput) (*GetGroupOutput, error) {
+	return c.WaitGroupRunningWithContext(context.Background(), input)
+}
+
+// WaitGroupRunningWithContext 等待group的Container.Status变为Running，状态为Error或者Failed时返回WaitFailedError
+func (c *Pipeline) WaitGroupRunningWithContext(ctx context.Context, input *WaitGroupRunningInput) (output *GetGroupOutput, err error) {
+	err = input.wait(ctx, "group", input.GroupName, func(ctx context.Context) (bool, string, error) {
+		var err error
+		output, err = c.GetGroupWithContext(ctx, &GetGroupInput{PipelineToken: input.PipelineToken, GroupName: input.GroupName})
+		if err != nil {
+			return false, "", err
+		}
+		if output.Container == nil {
+			return false, "", nil
+		}
+		status := output.Container.Status
+		switch {
+		case strings.EqualFold(status, GroupStatusRunning):
+			return true, status, nil
+		case strings.EqualFold(status, GroupStatusError), strings.EqualFold(status, GroupStatusFailed):
+			return false, status, &WaitFailedError{Resource: "group", Name: input.GroupName, Status: status}
+		}
+		return false, status, nil
+	})
+	return
+}
+
+func (c *Pipeline) WaitJobRunFinished(input *WaitJobRunFinishedInput) (*JobHistory, error) {
+	return c.WaitJobRunFinishedWithContext(context.Background(), input)
+}
+
+// WaitJobRunFinishedWithContext 等待job的一次运行结束，运行成功时返回对应的JobHistory，
+// 失败或者被取消时同时返回JobHistory和WaitFailedError
+func (c *Pipeline) WaitJobRunFinishedWithContext(ctx context.Context, input *WaitJobRunFinishedInput) (output *JobHistory, err error) {
+	err = input.wait(ctx, "job", input.JobName, func(ctx context.Context) (bool, string, error) {
+		history, err := c.GetJobHistoryWithContext(ctx, &GetJobHistoryInput{PipelineToken: input.PipelineToken, JobName: input.JobName})
+		if err != nil {
+			return false, "", err
+		}
+		run := findJobRun(history.History, input.RunId)
+		if run == nil {
+			return false, "", nil
+		}
+		output = run
+		switch {
+		case strings.EqualFold(run.Status, JobRunStatusSuccessful):
+			return true, run.Status, nil
+		case strings.EqualFold(run.Status, JobRunStatusFailed), strings.EqualFold(run.Status, JobRunStatusCanceled):
+			return false, run.Status, &WaitFailedError{
+				Resource: "job",
+				Name:     fmt.Sprintf("%s(run %d)", input.JobName, run.RunId),
+				Status:   run.Status,
+				Message:  run.Message,
+			}
+		}
+		return false, run.Status, nil
+	})
+	return
+}
+
+func findJobRun(history []JobHistory, runId int64) (run *JobHistory) {
+	for i := range history {
+		if runId != 0 && history[i].RunId == runId {
+			return &history[i]
+		}
+		if runId == 0 && (run == nil || history[i].RunId > run.RunId) {
+			run = &history[i]
+		}
+	}
+	return
+}
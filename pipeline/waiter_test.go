@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qiniu/pandora-go-sdk/base"
+)
+
+// statusServer 在前pending次请求返回pendingBody，之后返回body
+type statusServer struct {
+	calls       int32
+	pending     int32
+	pendingCode int
+	pendingBody string
+	body        string
+}
+
+func (s *statusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(base.HTTPHeaderContentType, base.ContentTypeJson)
+	if atomic.AddInt32(&s.calls, 1) <= s.pending {
+		w.WriteHeader(s.pendingCode)
+		w.Write([]byte(s.pendingBody))
+		return
+	}
+	w.Write([]byte(s.body))
+}
+
+func TestWaitRepoReady(t *testing.T) {
+	s := &statusServer{
+		pending:     2,
+		pendingCode: http.StatusAccepted,
+		pendingBody: `{"error":"E18124: repo is being created"}`,
+		body:        `{"region":"nb","schema":[{"key":"f1","valtype":"string"}]}`,
+	}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+
+	output, err := c.WaitRepoReady(&WaitRepoReadyInput{RepoName: "repo", WaitOption: WaitOption{Interval: time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Region != "nb" || atomic.LoadInt32(&s.calls) != 3 {
+		t.Fatalf("unexpected output %+v after %d calls", output, s.calls)
+	}
+}
+
+func TestWaitGroupRunningTimeout(t *testing.T) {
+	s := &statusServer{body: `{"region":"nb","container":{"type":"M16C4","count":1,"status":"Starting"}}`}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+
+	_, err := c.WaitGroupRunning(&WaitGroupRunningInput{
+		GroupName:  "group",
+		WaitOption: WaitOption{Interval: time.Millisecond, Timeout: 20 * time.Millisecond},
+	})
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("expect ErrWaitTimeout, got %v", err)
+	}
+}
+
+func TestWaitJobRunFinished(t *testing.T) {
+	s := &statusServer{
+		pending:     1,
+		pendingCode: http.StatusOK,
+		pendingBody: `{"total":1,"history":[{"id":1,"status":"Running"}]}`,
+		body:        `{"total":2,"history":[{"id":2,"status":"Failed","message":"oom"},{"id":1,"status":"Successful"}]}`,
+	}
+	c, ts := newTestPipeline(t, s)
+	defer ts.Close()
+
+	run, err := c.WaitJobRunFinished(&WaitJobRunFinishedInput{JobName: "job", RunId: 1, WaitOption: WaitOption{Interval: time.Millisecond}})
+	if err != nil || run.RunId != 1 {
+		t.Fatalf("expect run 1 successful, got %+v %v", run, err)
+	}
+
+	run, err = c.WaitJobRunFinished(&WaitJobRunFinishedInput{JobName: "job", WaitOption: WaitOption{Interval: time.Millisecond}})
+	var failed *WaitFailedError
+	if !errors.As(err, &failed) || failed.Status != "Failed" || failed.Message != "oom" || run.RunId != 2 {
+		t.Fatalf("expect run 2 failed, got %+v %v", run, err)
+	}
+}
@@ -137,6 +137,36 @@ func TestRepo(t *testing.T) {
 	}
 }
 
+func TestRepoConfig(t *testing.T) {
+	repoName := "repo_config"
+	err := client.CreateRepo(&CreateRepoInput{
+		RepoName:  repoName,
+		Region:    region,
+		Schema:    defaultRepoSchema,
+		Retention: "2d",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = client.PutRepoConfig(&PutRepoConfigInput{RepoName: repoName, TimeFieldName: "f3"})
+	if err != nil {
+		t.Error(err)
+	}
+	configOutput, err := client.GetRepoConfig(&GetRepoConfigInput{RepoName: repoName})
+	if err != nil {
+		t.Error(err)
+	}
+	if configOutput == nil || configOutput.TimeFieldName != "f3" {
+		t.Errorf("time field name should be f3 but %v", configOutput)
+	}
+
+	err = client.DeleteRepo(&DeleteRepoInput{RepoName: repoName})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 func TestSendAndQueryLog(t *testing.T) {
 	repoName := "repo_send_log"
 	createInput := &CreateRepoInput{
@@ -659,6 +659,37 @@ func TestTransform(t *testing.T) {
 	}
 }
 
+func TestVerifyTransform(t *testing.T) {
+	output, err := client.VerifyTransform(&pipeline.VerifyTransformInput{
+		Schema: defaultRepoSchema,
+		Spec: &pipeline.TransformSpec{
+			Mode: "sql",
+			Code: "select f1 from stream",
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if output == nil || len(output.Schema) == 0 {
+		t.Error("schema in verifyTransformOutput should not be empty")
+	}
+}
+
+func TestVerifyExport(t *testing.T) {
+	err := client.VerifyExport(&pipeline.VerifyExportInput{
+		Schema: defaultRepoSchema,
+		Spec: &pipeline.ExportTsdbSpec{
+			DestRepoName: "dest_repo",
+			SeriesName:   "series",
+			Tags:         map[string]string{"tag1": "#f1"},
+			Fields:       map[string]string{"field1": "#f2"},
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 func TestExport(t *testing.T) {
 	repoName := "repo_for_export"
 	createRepoInput := &pipeline.CreateRepoInput{
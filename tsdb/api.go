@@ -1,15 +1,20 @@
 package tsdb
 
 import (
+	"context"
 	"os"
 
 	. "github.com/qiniu/pandora-go-sdk/base"
 )
 
-func (c *Tsdb) CreateRepo(input *CreateRepoInput) (err error) {
+func (c *Tsdb) CreateRepo(input *CreateRepoInput) error {
+	return c.CreateRepoWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) CreateRepoWithContext(ctx context.Context, input *CreateRepoInput) (err error) {
 	op := c.newOperation(OpCreateRepo, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -17,26 +22,38 @@ func (c *Tsdb) CreateRepo(input *CreateRepoInput) (err error) {
 	return req.Send()
 }
 
-func (c *Tsdb) GetRepo(input *GetRepoInput) (output *GetRepoOutput, err error) {
+func (c *Tsdb) GetRepo(input *GetRepoInput) (*GetRepoOutput, error) {
+	return c.GetRepoWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) GetRepoWithContext(ctx context.Context, input *GetRepoInput) (output *GetRepoOutput, err error) {
 	op := c.newOperation(OpGetRepo, input.RepoName)
 
 	output = &GetRepoOutput{}
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	return output, req.Send()
 }
 
-func (c *Tsdb) ListRepos(input *ListReposInput) (output *ListReposOutput, err error) {
+func (c *Tsdb) ListRepos(input *ListReposInput) (*ListReposOutput, error) {
+	return c.ListReposWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) ListReposWithContext(ctx context.Context, input *ListReposInput) (output *ListReposOutput, err error) {
 	op := c.newOperation(OpListRepos)
 
 	output = &ListReposOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Tsdb) UpdateRepoMetadata(input *UpdateRepoMetadataInput) (err error) {
+func (c *Tsdb) UpdateRepoMetadata(input *UpdateRepoMetadataInput) error {
+	return c.UpdateRepoMetadataWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) UpdateRepoMetadataWithContext(ctx context.Context, input *UpdateRepoMetadataInput) (err error) {
 	op := c.newOperation(OpUpdateRepoMetadata, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -44,24 +61,36 @@ func (c *Tsdb) UpdateRepoMetadata(input *UpdateRepoMetadataInput) (err error) {
 	return req.Send()
 }
 
-func (c *Tsdb) DeleteRepoMetadata(input *DeleteRepoMetadataInput) (err error) {
+func (c *Tsdb) DeleteRepoMetadata(input *DeleteRepoMetadataInput) error {
+	return c.DeleteRepoMetadataWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) DeleteRepoMetadataWithContext(ctx context.Context, input *DeleteRepoMetadataInput) (err error) {
 	op := c.newOperation(OpDeleteRepoMetadata, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Tsdb) DeleteRepo(input *DeleteRepoInput) (err error) {
+func (c *Tsdb) DeleteRepo(input *DeleteRepoInput) error {
+	return c.DeleteRepoWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) DeleteRepoWithContext(ctx context.Context, input *DeleteRepoInput) (err error) {
 	op := c.newOperation(OpDeleteRepo, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Tsdb) CreateSeries(input *CreateSeriesInput) (err error) {
+func (c *Tsdb) CreateSeries(input *CreateSeriesInput) error {
+	return c.CreateSeriesWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) CreateSeriesWithContext(ctx context.Context, input *CreateSeriesInput) (err error) {
 	op := c.newOperation(OpCreateSeries, input.RepoName, input.SeriesName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -69,19 +98,27 @@ func (c *Tsdb) CreateSeries(input *CreateSeriesInput) (err error) {
 	return req.Send()
 }
 
-func (c *Tsdb) ListSeries(input *ListSeriesInput) (output *ListSeriesOutput, err error) {
+func (c *Tsdb) ListSeries(input *ListSeriesInput) (*ListSeriesOutput, error) {
+	return c.ListSeriesWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) ListSeriesWithContext(ctx context.Context, input *ListSeriesInput) (output *ListSeriesOutput, err error) {
 	op := c.newOperation(OpListSeries, input.RepoName)
 
 	output = &ListSeriesOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 
 }
 
-func (c *Tsdb) UpdateSeriesMetadata(input *UpdateSeriesMetadataInput) (err error) {
+func (c *Tsdb) UpdateSeriesMetadata(input *UpdateSeriesMetadataInput) error {
+	return c.UpdateSeriesMetadataWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) UpdateSeriesMetadataWithContext(ctx context.Context, input *UpdateSeriesMetadataInput) (err error) {
 	op := c.newOperation(OpUpdateSeriesMetadata, input.RepoName, input.SeriesName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -89,24 +126,36 @@ func (c *Tsdb) UpdateSeriesMetadata(input *UpdateSeriesMetadataInput) (err error
 	return req.Send()
 }
 
-func (c *Tsdb) DeleteSeriesMetadata(input *DeleteSeriesMetadataInput) (err error) {
+func (c *Tsdb) DeleteSeriesMetadata(input *DeleteSeriesMetadataInput) error {
+	return c.DeleteSeriesMetadataWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) DeleteSeriesMetadataWithContext(ctx context.Context, input *DeleteSeriesMetadataInput) (err error) {
 	op := c.newOperation(OpDeleteSeriesMetadata, input.RepoName, input.SeriesName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Tsdb) DeleteSeries(input *DeleteSeriesInput) (err error) {
+func (c *Tsdb) DeleteSeries(input *DeleteSeriesInput) error {
+	return c.DeleteSeriesWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) DeleteSeriesWithContext(ctx context.Context, input *DeleteSeriesInput) (err error) {
 	op := c.newOperation(OpDeleteSeries, input.RepoName, input.SeriesName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Tsdb) CreateView(input *CreateViewInput) (err error) {
+func (c *Tsdb) CreateView(input *CreateViewInput) error {
+	return c.CreateViewWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) CreateViewWithContext(ctx context.Context, input *CreateViewInput) (err error) {
 	op := c.newOperation(OpCreateView, input.RepoName, input.ViewName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
@@ -114,84 +163,135 @@ func (c *Tsdb) CreateView(input *CreateViewInput) (err error) {
 	return req.Send()
 }
 
-func (c *Tsdb) ListView(input *ListViewInput) (output *ListViewOutput, err error) {
+func (c *Tsdb) ListView(input *ListViewInput) (*ListViewOutput, error) {
+	return c.ListViewWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) ListViewWithContext(ctx context.Context, input *ListViewInput) (output *ListViewOutput, err error) {
 	op := c.newOperation(OpListView, input.RepoName)
 
 	output = &ListViewOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Tsdb) GetView(input *GetViewInput) (output *GetViewOutput, err error) {
+func (c *Tsdb) GetView(input *GetViewInput) (*GetViewOutput, error) {
+	return c.GetViewWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) GetViewWithContext(ctx context.Context, input *GetViewInput) (output *GetViewOutput, err error) {
 	op := c.newOperation(OpGetView, input.RepoName, input.ViewName)
 
 	output = &GetViewOutput{}
-	req := c.newRequest(op, input.Token, &output)
+	req := c.newRequest(ctx, op, input.Token, &output)
 	return output, req.Send()
 }
 
-func (c *Tsdb) DeleteView(input *DeleteViewInput) (err error) {
+func (c *Tsdb) DeleteView(input *DeleteViewInput) error {
+	return c.DeleteViewWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) DeleteViewWithContext(ctx context.Context, input *DeleteViewInput) (err error) {
 	op := c.newOperation(OpDeleteView, input.RepoName, input.ViewName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	return req.Send()
 }
 
-func (c *Tsdb) PostPoints(input *PostPointsInput) (err error) {
+func (c *Tsdb) PostPoints(input *PostPointsInput) error {
+	return c.PostPointsWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) PostPointsWithContext(ctx context.Context, input *PostPointsInput) (err error) {
 	op := c.newOperation(OpWritePoints, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	req.SetBufferBody(input.Points.Buffer())
 	req.SetHeader(HTTPHeaderContentType, ContentTypeText)
+	req.SetFlowLimiter(c.flowLimit)
+	req.SetReqLimiter(c.reqLimit)
 	return req.Send()
 }
 
-func (c *Tsdb) QueryPoints(input *QueryInput) (output *QueryOutput, err error) {
+func (c *Tsdb) QueryPoints(input *QueryInput) (*QueryOutput, error) {
+	return c.QueryPointsWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) QueryPointsWithContext(ctx context.Context, input *QueryInput) (output *QueryOutput, err error) {
 	op := c.newOperation(OpQueryPoints, input.RepoName)
 
 	output = &QueryOutput{}
 
-	req := c.newRequest(op, input.Token, output)
+	req := c.newRequest(ctx, op, input.Token, output)
 	if err = req.SetVariantBody(input); err != nil {
 		return
 	}
 	req.SetHeader(HTTPHeaderContentType, ContentTypeJson)
-
+	req.SetFlowLimiter(c.flowLimit)
+	req.SetReqLimiter(c.reqLimit)
 	return output, req.Send()
 }
 
-func (c *Tsdb) PostPointsFromFile(input *PostPointsFromFileInput) (err error) {
+func (c *Tsdb) PostPointsFromFile(input *PostPointsFromFileInput) error {
+	return c.PostPointsFromFileWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) PostPointsFromFileWithContext(ctx context.Context, input *PostPointsFromFileInput) (err error) {
 	op := c.newOperation(OpWritePoints, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	file, err := os.Open(input.FilePath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
+	stfile, err := file.Stat()
+	if err != nil {
+		return
+	}
+	req.SetBodyLength(stfile.Size())
 	req.SetReaderBody(file)
 	req.SetHeader(HTTPHeaderContentType, ContentTypeText)
+	req.SetFlowLimiter(c.flowLimit)
+	req.SetReqLimiter(c.reqLimit)
 	return req.Send()
 }
 
-func (c *Tsdb) PostPointsFromReader(input *PostPointsFromReaderInput) (err error) {
+func (c *Tsdb) PostPointsFromReader(input *PostPointsFromReaderInput) error {
+	return c.PostPointsFromReaderWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) PostPointsFromReaderWithContext(ctx context.Context, input *PostPointsFromReaderInput) (err error) {
 	op := c.newOperation(OpWritePoints, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
+	req.SetBodyLength(input.BodyLength)
 	req.SetReaderBody(input.Reader)
 	req.SetHeader(HTTPHeaderContentType, ContentTypeText)
+	req.SetFlowLimiter(c.flowLimit)
+	req.SetReqLimiter(c.reqLimit)
 	return req.Send()
 }
 
-func (c *Tsdb) PostPointsFromBytes(input *PostPointsFromBytesInput) (err error) {
+func (c *Tsdb) PostPointsFromBytes(input *PostPointsFromBytesInput) error {
+	return c.PostPointsFromBytesWithContext(context.Background(), input)
+}
+
+func (c *Tsdb) PostPointsFromBytesWithContext(ctx context.Context, input *PostPointsFromBytesInput) (err error) {
 	op := c.newOperation(OpWritePoints, input.RepoName)
 
-	req := c.newRequest(op, input.Token, nil)
+	req := c.newRequest(ctx, op, input.Token, nil)
 	req.SetBufferBody(input.Buffer)
 	req.SetHeader(HTTPHeaderContentType, ContentTypeText)
+	req.SetFlowLimiter(c.flowLimit)
+	req.SetReqLimiter(c.reqLimit)
 	return req.Send()
 }
 
 func (c *Tsdb) MakeToken(desc *TokenDesc) (string, error) {
-	return MakeTokenInternal(c.Config.Ak, c.Config.Sk, desc)
+	creds, err := c.Config.RetrieveCredentials()
+	if err != nil {
+		return "", err
+	}
+	return MakeTokenWithClock(creds.AccessKey, creds.SecretKey, desc, c.Config.Clock)
 }
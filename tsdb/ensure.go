@@ -0,0 +1,81 @@
+package tsdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/qiniu/pandora-go-sdk/base/ensure"
+)
+
+// MetadataConflict 描述一个已存在的metadata与期望的值不同，Existing和Desired为string
+type MetadataConflict = ensure.Conflict
+
+// MetadataDiff 是期望的metadata与repo当前metadata的差异，值不同的metadata不会被修改
+type MetadataDiff = ensure.Diff
+
+type EnsureRepoOutput = ensure.Output
+
+// DiffMetadata 比较repo当前的metadata和期望的metadata，返回差异以及合并了缺少的key之后的metadata
+func DiffMetadata(existing, desired map[string]string) (diff MetadataDiff, merged map[string]string) {
+	merged = make(map[string]string, len(existing)+len(desired))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	existingKeys, desiredKeys := sortedKeys(existing), sortedKeys(desired)
+	ensure.DiffKeys("", existingKeys, desiredKeys, &diff, func(i, j int) {
+		k := desiredKeys[j]
+		if i < 0 {
+			merged[k] = desired[k]
+			return
+		}
+		if e, d := existing[k], desired[k]; e != d {
+			diff.Conflicts = append(diff.Conflicts, MetadataConflict{Key: k, Existing: e, Desired: d, Reason: fmt.Sprintf("%q cannot be changed to %q", e, d)})
+		}
+	})
+	return
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Tsdb) EnsureRepo(input *CreateRepoInput) (*EnsureRepoOutput, error) {
+	return c.EnsureRepoWithContext(context.Background(), input)
+}
+
+// EnsureRepoWithContext 按ensure.Ensure的流程创建repo，或者通过UpdateRepoMetadata添加缺少的metadata，
+// 已有的metadata值不同时作为冲突返回
+func (c *Tsdb) EnsureRepoWithContext(ctx context.Context, input *CreateRepoInput) (*EnsureRepoOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	var repo *GetRepoOutput
+	var merged map[string]string
+	return ensure.Ensure(ctx, ensure.Repo{
+		Name: input.RepoName,
+		Get: func(ctx context.Context) (err error) {
+			repo, err = c.GetRepoWithContext(ctx, &GetRepoInput{TsdbToken: input.TsdbToken, RepoName: input.RepoName})
+			return
+		},
+		Create: func(ctx context.Context) error {
+			return c.CreateRepoWithContext(ctx, input)
+		},
+		Diff: func() (diff MetadataDiff) {
+			diff, merged = DiffMetadata(repo.Metadata, input.Metadata)
+			return
+		},
+		Update: func(ctx context.Context) error {
+			return c.UpdateRepoMetadataWithContext(ctx, &UpdateRepoMetadataInput{
+				TsdbToken: input.TsdbToken,
+				RepoName:  input.RepoName,
+				Metadata:  merged,
+			})
+		},
+	})
+}
@@ -1,49 +1,97 @@
 package tsdb
 
 import (
+	"context"
+
 	"github.com/qiniu/pandora-go-sdk/base"
 )
 
 type TsdbAPI interface {
 	CreateRepo(*CreateRepoInput) error
 
+	CreateRepoWithContext(context.Context, *CreateRepoInput) error
+
+	EnsureRepo(*CreateRepoInput) (*EnsureRepoOutput, error)
+
+	EnsureRepoWithContext(context.Context, *CreateRepoInput) (*EnsureRepoOutput, error)
+
 	GetRepo(*GetRepoInput) (*GetRepoOutput, error)
 
+	GetRepoWithContext(context.Context, *GetRepoInput) (*GetRepoOutput, error)
+
 	ListRepos(*ListReposInput) (*ListReposOutput, error)
 
+	ListReposWithContext(context.Context, *ListReposInput) (*ListReposOutput, error)
+
 	UpdateRepoMetadata(*UpdateRepoMetadataInput) error
 
+	UpdateRepoMetadataWithContext(context.Context, *UpdateRepoMetadataInput) error
+
 	DeleteRepoMetadata(*DeleteRepoMetadataInput) error
 
+	DeleteRepoMetadataWithContext(context.Context, *DeleteRepoMetadataInput) error
+
 	DeleteRepo(*DeleteRepoInput) error
 
+	DeleteRepoWithContext(context.Context, *DeleteRepoInput) error
+
 	CreateSeries(*CreateSeriesInput) error
 
+	CreateSeriesWithContext(context.Context, *CreateSeriesInput) error
+
 	ListSeries(*ListSeriesInput) (*ListSeriesOutput, error)
 
+	ListSeriesWithContext(context.Context, *ListSeriesInput) (*ListSeriesOutput, error)
+
 	UpdateSeriesMetadata(*UpdateSeriesMetadataInput) error
 
+	UpdateSeriesMetadataWithContext(context.Context, *UpdateSeriesMetadataInput) error
+
 	DeleteSeriesMetadata(*DeleteSeriesMetadataInput) error
 
+	DeleteSeriesMetadataWithContext(context.Context, *DeleteSeriesMetadataInput) error
+
 	DeleteSeries(*DeleteSeriesInput) error
 
+	DeleteSeriesWithContext(context.Context, *DeleteSeriesInput) error
+
 	CreateView(*CreateViewInput) error
 
+	CreateViewWithContext(context.Context, *CreateViewInput) error
+
 	ListView(*ListViewInput) (*ListViewOutput, error)
 
+	ListViewWithContext(context.Context, *ListViewInput) (*ListViewOutput, error)
+
 	GetView(*GetViewInput) (*GetViewOutput, error)
 
+	GetViewWithContext(context.Context, *GetViewInput) (*GetViewOutput, error)
+
 	DeleteView(*DeleteViewInput) error
 
+	DeleteViewWithContext(context.Context, *DeleteViewInput) error
+
 	PostPoints(*PostPointsInput) error
 
+	PostPointsWithContext(context.Context, *PostPointsInput) error
+
 	PostPointsFromFile(*PostPointsFromFileInput) error
 
+	PostPointsFromFileWithContext(context.Context, *PostPointsFromFileInput) error
+
 	PostPointsFromReader(*PostPointsFromReaderInput) error
 
+	PostPointsFromReaderWithContext(context.Context, *PostPointsFromReaderInput) error
+
 	PostPointsFromBytes(*PostPointsFromBytesInput) error
 
+	PostPointsFromBytesWithContext(context.Context, *PostPointsFromBytesInput) error
+
 	QueryPoints(*QueryInput) (*QueryOutput, error)
 
+	QueryPointsWithContext(context.Context, *QueryInput) (*QueryOutput, error)
+
 	MakeToken(*base.TokenDesc) (string, error)
+
+	Close() error
 }
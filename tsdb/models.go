@@ -254,8 +254,9 @@ type PostPointsFromFileInput struct {
 
 type PostPointsFromReaderInput struct {
 	TsdbToken
-	RepoName string
-	Reader   io.ReadSeeker
+	RepoName   string
+	Reader     io.ReadSeeker
+	BodyLength int64
 }
 
 type PostPointsFromBytesInput struct {
@@ -1,14 +1,14 @@
 package tsdb
 
 import (
+	"context"
 	"fmt"
-	"net"
 	"net/http"
 	"strings"
-	"time"
 
 	. "github.com/qiniu/pandora-go-sdk/base"
 	"github.com/qiniu/pandora-go-sdk/base/config"
+	"github.com/qiniu/pandora-go-sdk/base/ratelimit"
 	"github.com/qiniu/pandora-go-sdk/base/request"
 )
 
@@ -17,6 +17,8 @@ var builder errBuilder
 type Tsdb struct {
 	Config     *config.Config
 	HTTPClient *http.Client
+	reqLimit   *ratelimit.Limiter
+	flowLimit  *ratelimit.Limiter
 }
 
 func NewConfig() *config.Config {
@@ -27,7 +29,24 @@ func New(c *config.Config) (TsdbAPI, error) {
 	return newClient(c)
 }
 
+func (c *Tsdb) Close() (err error) {
+	if c.reqLimit != nil {
+		err = c.reqLimit.Close()
+		if err != nil {
+			c.Config.Logger.Errorf("Close reqLimit error %v", err)
+		}
+	}
+	if c.flowLimit != nil {
+		err = c.flowLimit.Close()
+		if err != nil {
+			c.Config.Logger.Errorf("Close flowLimit error %v", err)
+		}
+	}
+	return
+}
+
 func newClient(c *config.Config) (p *Tsdb, err error) {
+	c = c.ForService(config.ServiceTsdb)
 	if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
 		err = fmt.Errorf("endpoint should start with 'http://' or 'https://'")
 		return
@@ -37,25 +56,19 @@ func newClient(c *config.Config) (p *Tsdb, err error) {
 		return
 	}
 
-	var t = &http.Transport{
-		Dial: (&net.Dialer{
-			Timeout:   c.DialTimeout,
-			KeepAlive: 30 * time.Second,
-		}).Dial,
-		ResponseHeaderTimeout: c.ResponseTimeout,
-	}
-
 	p = &Tsdb{
 		Config:     c,
-		HTTPClient: &http.Client{Transport: t},
+		HTTPClient: c.NewHTTPClient(),
 	}
-
+	p.reqLimit = c.NewRequestLimiter()
+	p.flowLimit = c.NewFlowLimiter()
 	return
 }
 
-func (c *Tsdb) newRequest(op *request.Operation, token string, v interface{}) *request.Request {
+func (c *Tsdb) newRequest(ctx context.Context, op *request.Operation, token string, v interface{}) *request.Request {
 	req := request.New(c.Config, c.HTTPClient, op, token, builder, v)
 	req.Data = v
+	req.SetContext(ctx)
 	return req
 }
 